@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ma111e/hugo-notion/internal/sync"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [run] [post...]",
+	Short: "Restore posts moved to the trash by a sync",
+	Long: `Restore the posts that syncs moved to the trash of the state directory.
+Without arguments, the trash is listed. With a run, all its posts are restored, or only the given ones.`,
+	RunE: runRestore,
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+}
+
+func runRestore(_ *cobra.Command, args []string) error {
+	stateDir := viper.GetString("state_dir")
+
+	if len(args) == 0 {
+		runs, err := sync.LoadTrash(stateDir)
+		if err != nil {
+			return fmt.Errorf("failed to read the trash: %v", err)
+		}
+		if len(runs) == 0 {
+			fmt.Println("The trash is empty")
+			return nil
+		}
+
+		for _, run := range runs {
+			fmt.Println(run.Name)
+			for _, post := range run.Posts {
+				fmt.Printf("  %s\n", post)
+			}
+		}
+		return nil
+	}
+
+	lock, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	results, err := sync.Restore(stateDir, viper.GetString("content_dir"), args[0], args[1:])
+	for _, result := range results {
+		if result.Message != "" {
+			fmt.Printf("%s: %s (%s)\n", result.Status, result.Path, result.Message)
+		} else {
+			fmt.Printf("%s: %s\n", result.Status, result.Path)
+		}
+	}
+
+	return err
+}
@@ -5,17 +5,45 @@ import (
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"time"
 )
 
 var (
-	cfgFile         string
-	contentDir      string
-	notionURL       string
-	notionToken     string
-	withFrontMatter bool
-	interactive     bool
-	useS3Images     bool
-	postsBaseURI    string
+	cfgFile          string
+	contentDir       string
+	notionURL        string
+	notionToken      string
+	withFrontMatter  bool
+	mergeFrontMatter bool
+	interactive      bool
+	useS3Images      bool
+	postsBaseURI     string
+	stateDir         string
+	optimizeImages   bool
+	imageMaxWidth    int
+	imageMaxHeight   int
+	imageQuality     int
+	imageWebP        bool
+	imageWidths      []int
+	imagePlaceholder string
+	imageFrontMatter bool
+	orphanedFiles    string
+	dateProperty     string
+	timezone         string
+	dateFormat       string
+	publishProperty  string
+	pages            []string
+	pagesFile        string
+	selection        string
+	saveSelection    string
+	resultsScreen    bool
+	conflicts        string
+	deletePolicy     string
+	trashRetention   int
+	maxDeletions     string
+	forceDelete      bool
+	lockMode         string
+	lockTimeout      time.Duration
 )
 
 func init() {
@@ -24,24 +52,78 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&notionURL, "url", "u", "", "Notion page URL to sync")
 	rootCmd.PersistentFlags().StringVarP(&notionToken, "token", "t", "", "Notion token of the integration connected to the root page to fetch")
 	rootCmd.PersistentFlags().BoolVarP(&withFrontMatter, "add-front-matter", "a", false, "add front matter in markdown files")
+	rootCmd.PersistentFlags().BoolVar(&mergeFrontMatter, "merge-front-matter", false, "keep the front matter keys not managed by hugo-notion, such as fields added by hand")
 	rootCmd.PersistentFlags().BoolVarP(&interactive, "interactive", "i", false, "enable interactive page selection")
-	rootCmd.PersistentFlags().BoolVar(&useS3Images, "s3-images", false, "use S3 for image storage (legacy behavior)")
+	rootCmd.PersistentFlags().BoolVar(&useS3Images, "s3-images", false, "upload images to an S3-compatible bucket instead of the page bundles")
 	rootCmd.PersistentFlags().StringVar(&postsBaseURI, "posts-base-uri", "/posts", "base URI for posts in the generated site")
+	rootCmd.PersistentFlags().StringVar(&stateDir, "state-dir", ".hugo-notion", "directory where the sync state is stored")
+	rootCmd.PersistentFlags().BoolVar(&optimizeImages, "optimize-images", false, "resize and re-encode downloaded images")
+	rootCmd.PersistentFlags().IntVar(&imageMaxWidth, "image-max-width", 0, "maximum width of optimized images (0 for no limit)")
+	rootCmd.PersistentFlags().IntVar(&imageMaxHeight, "image-max-height", 0, "maximum height of optimized images (0 for no limit)")
+	rootCmd.PersistentFlags().IntVar(&imageQuality, "image-quality", 85, "JPEG quality of optimized images")
+	rootCmd.PersistentFlags().BoolVar(&imageWebP, "image-webp", false, "generate WebP variants of optimized images")
+	rootCmd.PersistentFlags().IntSliceVar(&imageWidths, "image-srcset-widths", nil, "widths of the resized variants listed in the image srcset")
+	rootCmd.PersistentFlags().StringVar(&imagePlaceholder, "image-placeholder", "", "low-quality image placeholder to compute: blurhash or lqip")
+	rootCmd.PersistentFlags().BoolVar(&imageFrontMatter, "image-front-matter", false, "list the images of a page in its front matter")
+	rootCmd.PersistentFlags().StringVar(&dateProperty, "date-property", "", "Date property holding the publication date of database pages (default is the creation time)")
+	rootCmd.PersistentFlags().StringVar(&timezone, "timezone", "", "timezone of the front matter dates, e.g. Europe/Paris (default is UTC)")
+	rootCmd.PersistentFlags().StringVar(&dateFormat, "date-format", "", "Go layout of the front matter dates (default is RFC 3339)")
+	rootCmd.PersistentFlags().StringVar(&publishProperty, "publish-property", "", "Status, Select or Checkbox property deciding whether database pages are published")
+	rootCmd.PersistentFlags().StringArrayVarP(&pages, "page", "p", nil, "URL or ID of a page or database to sync, instead of the whole root page (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&pagesFile, "pages-file", "", "file listing the URLs or IDs of the pages to sync, one per line (- for stdin)")
+	rootCmd.PersistentFlags().StringVar(&selection, "selection", "", "sync a saved selection, or pre-check it in interactive mode")
+	rootCmd.PersistentFlags().StringVar(&saveSelection, "save-selection", "", "save the selected pages under this name")
+	rootCmd.PersistentFlags().BoolVar(&resultsScreen, "results-screen", true, "keep the sync results open to browse, retry and edit them (only in a terminal)")
+	rootCmd.PersistentFlags().StringVar(&conflicts, "conflicts", "keep", "what to do with posts edited locally: keep, overwrite or side-by-side")
+	rootCmd.PersistentFlags().StringVar(&deletePolicy, "delete-policy", "trash", "what to do with the posts of removed pages: trash, draft or delete")
+	rootCmd.PersistentFlags().IntVar(&trashRetention, "trash-retention", 10, "number of sync runs kept in the trash (0 to keep them all)")
+	rootCmd.PersistentFlags().StringVar(&maxDeletions, "max-deletions", "50%", "maximum number or percentage of posts a full sync may delete (empty for no limit)")
+	rootCmd.PersistentFlags().BoolVar(&forceDelete, "force-delete", false, "delete the posts of removed pages even above --max-deletions")
+	rootCmd.PersistentFlags().StringVar(&lockMode, "lock-mode", "exit", "what to do when another sync is running: exit, wait or queue")
+	rootCmd.PersistentFlags().DurationVar(&lockTimeout, "lock-timeout", 10*time.Minute, "how long to wait for another sync to finish (0 for no limit)")
+	rootCmd.PersistentFlags().StringVar(&orphanedFiles, "orphaned-files", "report", "what to do with bundle files no longer used by their page: report, delete or keep")
 
 	viper.BindPFlag("content_dir", rootCmd.PersistentFlags().Lookup("content-dir"))
 	viper.BindPFlag("notion_token", rootCmd.PersistentFlags().Lookup("token"))
 	viper.BindPFlag("notion_root_page", rootCmd.PersistentFlags().Lookup("url"))
-	viper.BindPFlag("add_front_matter", rootCmd.PersistentFlags().Lookup("front-matter"))
+	viper.BindPFlag("add_front_matter", rootCmd.PersistentFlags().Lookup("add-front-matter"))
+	viper.BindPFlag("merge_front_matter", rootCmd.PersistentFlags().Lookup("merge-front-matter"))
 	viper.BindPFlag("interactive", rootCmd.PersistentFlags().Lookup("interactive"))
 	viper.BindPFlag("s3_images", rootCmd.PersistentFlags().Lookup("s3-images"))
 	viper.BindPFlag("posts_base_uri", rootCmd.PersistentFlags().Lookup("posts-base-uri"))
+	viper.BindPFlag("state_dir", rootCmd.PersistentFlags().Lookup("state-dir"))
+	viper.BindPFlag("optimize_images", rootCmd.PersistentFlags().Lookup("optimize-images"))
+	viper.BindPFlag("image_max_width", rootCmd.PersistentFlags().Lookup("image-max-width"))
+	viper.BindPFlag("image_max_height", rootCmd.PersistentFlags().Lookup("image-max-height"))
+	viper.BindPFlag("image_quality", rootCmd.PersistentFlags().Lookup("image-quality"))
+	viper.BindPFlag("image_webp", rootCmd.PersistentFlags().Lookup("image-webp"))
+	viper.BindPFlag("image_srcset_widths", rootCmd.PersistentFlags().Lookup("image-srcset-widths"))
+	viper.BindPFlag("image_placeholder", rootCmd.PersistentFlags().Lookup("image-placeholder"))
+	viper.BindPFlag("image_front_matter", rootCmd.PersistentFlags().Lookup("image-front-matter"))
+	viper.BindPFlag("date_property", rootCmd.PersistentFlags().Lookup("date-property"))
+	viper.BindPFlag("timezone", rootCmd.PersistentFlags().Lookup("timezone"))
+	viper.BindPFlag("date_format", rootCmd.PersistentFlags().Lookup("date-format"))
+	viper.BindPFlag("publish_property", rootCmd.PersistentFlags().Lookup("publish-property"))
+	viper.BindPFlag("pages", rootCmd.PersistentFlags().Lookup("page"))
+	viper.BindPFlag("pages_file", rootCmd.PersistentFlags().Lookup("pages-file"))
+	viper.BindPFlag("selection", rootCmd.PersistentFlags().Lookup("selection"))
+	viper.BindPFlag("save_selection", rootCmd.PersistentFlags().Lookup("save-selection"))
+	viper.BindPFlag("results_screen", rootCmd.PersistentFlags().Lookup("results-screen"))
+	viper.BindPFlag("conflicts", rootCmd.PersistentFlags().Lookup("conflicts"))
+	viper.BindPFlag("delete_policy", rootCmd.PersistentFlags().Lookup("delete-policy"))
+	viper.BindPFlag("trash_retention", rootCmd.PersistentFlags().Lookup("trash-retention"))
+	viper.BindPFlag("max_deletions", rootCmd.PersistentFlags().Lookup("max-deletions"))
+	viper.BindPFlag("force_delete", rootCmd.PersistentFlags().Lookup("force-delete"))
+	viper.BindPFlag("lock_mode", rootCmd.PersistentFlags().Lookup("lock-mode"))
+	viper.BindPFlag("lock_timeout", rootCmd.PersistentFlags().Lookup("lock-timeout"))
+	viper.BindPFlag("orphaned_files", rootCmd.PersistentFlags().Lookup("orphaned-files"))
 }
 
 var rootCmd = &cobra.Command{
 	Use:   "hugo-notion",
 	Short: "Sync Notion pages to markdown files",
 	Long:  `A CLI tool to synchronize Notion pages and databases to markdown files`,
-	PreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		// godotenv's autoload is used
 		viper.SetEnvPrefix("HN")
 		viper.SetConfigFile(".hugo-notion.yml")
@@ -52,6 +134,11 @@ var rootCmd = &cobra.Command{
 		} else {
 			fmt.Println(err)
 		}
+
+		// Earlier versions read front_matter instead of the documented add_front_matter
+		if !viper.IsSet("add_front_matter") && viper.IsSet("front_matter") {
+			viper.Set("add_front_matter", viper.GetBool("front_matter"))
+		}
 	},
 	RunE: runSync,
 }
@@ -1,19 +1,28 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/term"
 	"github.com/jomei/notionapi"
 	"github.com/ma111e/hugo-notion/internal/sync"
 	"github.com/ma111e/hugo-notion/internal/tui"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"io"
 	"net/url"
+	"os"
+	"regexp"
 	"strings"
+	"time"
 )
 
+var pageIDRegex = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
 func runSync(_ *cobra.Command, _ []string) error {
 	var selectedPages []string
+	var markdownCache *sync.MarkdownCache
 
 	notionToken := viper.GetString("notion_token")
 	if notionToken == "" {
@@ -32,11 +41,43 @@ func runSync(_ *cobra.Command, _ []string) error {
 
 	client := notionapi.NewClient(notionapi.Token(notionToken))
 
-	isInteractive := viper.GetBool("interactive")
+	selectedPages, err = pagesFromFlags()
+	if err != nil {
+		return err
+	}
+
+	// Pages given on the command line skip the interactive selection
+	isInteractive := viper.GetBool("interactive") && len(selectedPages) == 0
+
+	selections, err := sync.LoadSelections(viper.GetString("state_dir"))
+	if err != nil {
+		return fmt.Errorf("failed to load the saved selections: %v", err)
+	}
+
+	// Interactive runs start from the last selection, or from the named one
+	preselected := selections.Last
+	if name := viper.GetString("selection"); name != "" {
+		namedPages, err := selections.Get(name)
+		if err != nil {
+			return err
+		}
+
+		if isInteractive {
+			preselected = namedPages
+		} else {
+			selectedPages = append(selectedPages, namedPages...)
+		}
+	}
 
 	if isInteractive {
 		// Run selection UI
-		selectionModel := tui.NewSelectionModel(client, pageID)
+		keymap, err := tui.LoadKeyMap()
+		if err != nil {
+			return err
+		}
+
+		selectionModel := tui.NewSelectionModelWithSelection(client, pageID, preselected)
+		selectionModel.SetKeyMap(keymap)
 		p := tea.NewProgram(selectionModel, tea.WithAltScreen())
 
 		m, err := p.Run()
@@ -49,26 +90,84 @@ func runSync(_ *cobra.Command, _ []string) error {
 			return fmt.Errorf("unexpected model type")
 		}
 
+		if selModel.Aborted() {
+			return nil
+		}
+
+		markdownCache = selModel.MarkdownCache()
 		selectedPages = selModel.GetSelectedPages()
 		if len(selectedPages) == 0 {
 			fmt.Println("No pages selected, exiting...")
 			return nil
 		}
 
+		selections.Last = selectedPages
+	}
+
+	if name := viper.GetString("save_selection"); name != "" && len(selectedPages) > 0 {
+		selections.Named[name] = selectedPages
+	}
+
+	if isInteractive || viper.GetString("save_selection") != "" {
+		if err := selections.Save(); err != nil {
+			return fmt.Errorf("failed to save the selection: %v", err)
+		}
+	}
+
+	// The lock is only held while syncing, not while the results are shown
+	lock, err := acquireLock()
+	if err != nil {
+		return err
 	}
+
 	updates := make(chan sync.SyncResult)
 	syncer := sync.NewSyncerWithSelection(client, viper.GetString("content_dir"), selectedPages, updates)
+	syncer.UseMarkdownCache(markdownCache)
+
+	syncModel := tui.NewSyncModel()
+	// Scripts and CI runs are not kept waiting on the results
+	if viper.GetBool("results_screen") && term.IsTerminal(os.Stdin.Fd()) && term.IsTerminal(os.Stdout.Fd()) {
+		syncModel.SetResultsScreen(true)
+		syncModel.SetRetry(func(pageIDs []string) []sync.SyncResult {
+			// Nothing is printed while waiting, the results screen is shown
+			retryLock, err := sync.AcquireLock(viper.GetString("state_dir"), viper.GetString("lock_mode"), viper.GetDuration("lock_timeout"), nil)
+			if err != nil {
+				return []sync.SyncResult{{
+					PageTitle:   "Retry",
+					Status:      "Error",
+					Path:        viper.GetString("state_dir"),
+					LastUpdated: time.Now(),
+					Message:     err.Error(),
+				}}
+			}
+			defer retryLock.Release()
 
-	p := tea.NewProgram(tui.NewSyncModel())
+			retrySyncer := sync.NewSyncerWithSelection(client, viper.GetString("content_dir"), pageIDs, nil)
+			retrySyncer.UseMarkdownCache(markdownCache)
+			return retrySyncer.Sync(pageID)
+		})
+	}
+
+	progress := make(chan sync.Progress)
+	syncer.ReportProgress(progress)
+
+	p := tea.NewProgram(syncModel)
 	go func() {
 		go func() {
 			for update := range updates {
 				p.Send(update)
 			}
 		}()
+		go func() {
+			for update := range progress {
+				p.Send(update)
+			}
+		}()
 
 		results := syncer.Sync(pageID)
+		lock.Release()
 		close(updates)
+		close(progress)
 		p.Send(results)
 	}()
 
@@ -80,6 +179,66 @@ func runSync(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
+// acquireLock prevents other runs from syncing the content directory at the same time
+func acquireLock() (*sync.Lock, error) {
+	return sync.AcquireLock(viper.GetString("state_dir"), viper.GetString("lock_mode"), viper.GetDuration("lock_timeout"), func(holder sync.LockInfo) {
+		fmt.Printf("Waiting for another sync to finish (%s)...\n", holder)
+	})
+}
+
+// pagesFromFlags returns the IDs of the pages listed with --page and --pages-file
+func pagesFromFlags() ([]string, error) {
+	pages := viper.GetStringSlice("pages")
+
+	pagesFile := viper.GetString("pages_file")
+	if pagesFile != "" {
+		filePages, err := readPagesFile(pagesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pages file: %v", err)
+		}
+		// An empty list would turn the run into a full sync, deleting the other posts
+		if len(filePages) == 0 {
+			return nil, fmt.Errorf("no pages listed in %s", pagesFile)
+		}
+		pages = append(pages, filePages...)
+	}
+
+	var pageIDs []string
+	for _, page := range pages {
+		pageID := sync.NormalizePageID(page)
+		if !pageIDRegex.MatchString(pageID) {
+			return nil, fmt.Errorf("invalid page URL or ID: %q", page)
+		}
+		pageIDs = append(pageIDs, pageID)
+	}
+
+	return pageIDs, nil
+}
+
+// readPagesFile reads the non-empty lines of a pages file, ignoring # comments
+func readPagesFile(path string) ([]string, error) {
+	var reader io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	var pages []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			pages = append(pages, line)
+		}
+	}
+
+	return pages, scanner.Err()
+}
+
 func extractPageID(urlStr string) (string, error) {
 	parsedURL, err := url.ParseRequestURI(urlStr)
 	if err != nil {
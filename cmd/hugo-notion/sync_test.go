@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestPagesFromFlags(t *testing.T) {
+	const (
+		firstID  = "0123456789abcdef0123456789abcdef"
+		secondID = "fedcba9876543210fedcba9876543210"
+	)
+
+	tests := []struct {
+		name      string
+		pages     []string
+		pagesFile string
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name: "no pages",
+		},
+		{
+			name:  "IDs and URLs",
+			pages: []string{"01234567-89AB-CDEF-0123-456789ABCDEF", "https://www.notion.so/Post-fedcba9876543210fedcba9876543210?pvs=4"},
+			want:  []string{firstID, secondID},
+		},
+		{
+			name:    "invalid page",
+			pages:   []string{"https://www.notion.so/Post"},
+			wantErr: true,
+		},
+		{
+			name:      "pages file with comments and blank lines",
+			pages:     []string{firstID},
+			pagesFile: "# Posts to publish\n\nhttps://www.notion.so/Post-fedcba9876543210fedcba9876543210 # new\n",
+			want:      []string{firstID, secondID},
+		},
+		{
+			name:      "empty pages file",
+			pagesFile: "# nothing yet\n",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.Set("pages", tt.pages)
+			if tt.pagesFile != "" {
+				path := filepath.Join(t.TempDir(), "pages.txt")
+				if err := os.WriteFile(path, []byte(tt.pagesFile), 0644); err != nil {
+					t.Fatal(err)
+				}
+				viper.Set("pages_file", path)
+			}
+
+			got, err := pagesFromFlags()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("pagesFromFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pagesFromFlags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPagesFromFlagsMissingFile(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("pages_file", filepath.Join(t.TempDir(), "missing.txt"))
+
+	if _, err := pagesFromFlags(); err == nil {
+		t.Error("pagesFromFlags() succeeded, want an error")
+	}
+}
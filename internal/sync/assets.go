@@ -0,0 +1,390 @@
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Regular expression to find Markdown links, with the kind of attachment as an optional title.
+// Go regexps having no lookbehind, images are told apart by the character before the match.
+var assetRegex = regexp.MustCompile(`\[([^\]]*)\]\((\S+?)(?: "(file|pdf|video|audio)")?\)`)
+
+// Characters kept as is in the name of downloaded files
+var unsafeFilenameRegex = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// defaultAssetTemplates render the attachments of each kind. The video and audio shortcodes are
+// not built into Hugo and are expected to be provided by the theme.
+var defaultAssetTemplates = map[string]string{
+	"file":  `[[ printf "[%s](%s)" (or .Caption .Name) .Src ]]`,
+	"pdf":   `[[ printf "[%s](%s)" (or .Caption .Name) .Src ]]`,
+	"video": `{{< video src="[[ .Src ]]" caption="[[ attr .Caption ]]" >}}`,
+	"audio": `{{< audio src="[[ .Src ]]" caption="[[ attr .Caption ]]" >}}`,
+}
+
+// AssetInfo describes an attachment included in a page, as exposed to the asset templates
+type AssetInfo struct {
+	Kind    string
+	Src     string
+	Name    string
+	Caption string
+}
+
+// isNotionHosted reports whether a URL points to a file uploaded to Notion. Those URLs are
+// signed and expire after an hour, so the files must be downloaded to stay available.
+func isNotionHosted(fileURL string) bool {
+	parsedURL, err := url.Parse(fileURL)
+	if err != nil {
+		return false
+	}
+
+	host := parsedURL.Hostname()
+	switch {
+	case host == "file.notion.so":
+		return true
+	case strings.HasPrefix(host, "prod-files-secure.") && strings.HasSuffix(host, ".amazonaws.com"):
+		return true
+	case strings.HasSuffix(host, ".amazonaws.com") && strings.HasPrefix(parsedURL.Path, "/secure.notion-static.com/"):
+		return true
+	}
+
+	return false
+}
+
+// assetTemplates parses the asset templates, the configured asset_templates overriding the defaults
+func assetTemplates() (map[string]*template.Template, error) {
+	texts := make(map[string]string)
+	for kind, text := range defaultAssetTemplates {
+		texts[kind] = text
+	}
+
+	var errs []string
+	for kind, text := range viper.GetStringMapString("asset_templates") {
+		if _, ok := defaultAssetTemplates[kind]; !ok {
+			errs = append(errs, fmt.Sprintf("unknown asset kind %q", kind))
+			continue
+		}
+		texts[kind] = text
+	}
+
+	templates := make(map[string]*template.Template)
+	for kind, text := range texts {
+		tmpl, err := newShortcodeTemplate(kind, text)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", kind, err))
+			tmpl, _ = newShortcodeTemplate(kind, defaultAssetTemplates[kind])
+		}
+		templates[kind] = tmpl
+	}
+
+	if len(errs) > 0 {
+		return templates, fmt.Errorf("invalid asset templates, using the default ones: %s", strings.Join(errs, "; "))
+	}
+
+	return templates, nil
+}
+
+// assetFilename returns the name of an attachment in the page bundle, prefixed with its content hash
+func assetFilename(hash string, originalName string) string {
+	name := strings.Trim(unsafeFilenameRegex.ReplaceAllString(originalName, "-"), "-.")
+	name = strings.ReplaceAll(name, "-.", ".")
+	if name == "" {
+		return imageBaseName(hash)
+	}
+
+	return imageBaseName(hash) + "-" + name
+}
+
+// originalAssetName returns the unescaped filename of an attachment URL
+func originalAssetName(assetURL string) string {
+	parsedURL, err := url.Parse(assetURL)
+	if err != nil {
+		return ""
+	}
+
+	return path.Base(parsedURL.Path)
+}
+
+// resolveAsset makes an attachment available in the "files" folder of the page bundle and returns its filename.
+// Attachments already downloaded by a previous sync are reused without any request to Notion.
+func (s *Syncer) resolveAsset(assetURL string, page PageLocation) (string, error) {
+	filesDir := filepath.Join(page.Dir, "files")
+	sourceKey := imageSourceKey(assetURL)
+
+	if hash, ok := s.state.Sources[sourceKey]; ok {
+		if asset, ok := s.state.Assets[hash]; ok {
+			assetPath := filepath.Join(filesDir, asset.Name)
+			if _, err := os.Stat(assetPath); err == nil {
+				return asset.Name, nil
+			}
+
+			if existing, ok := s.state.assetFile(hash); ok {
+				if err := os.MkdirAll(filesDir, 0755); err == nil && linkOrCopy(existing, assetPath) == nil {
+					s.state.recordAsset(hash, assetPath)
+					return asset.Name, nil
+				}
+			}
+		}
+	}
+
+	hash, tmpPath, err := s.downloadAsset(assetURL, filesDir)
+	if err != nil {
+		return "", err
+	}
+
+	name := assetFilename(hash, originalAssetName(assetURL))
+	assetPath := filepath.Join(filesDir, name)
+	if err := os.Rename(tmpPath, assetPath); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+
+	s.state.Assets[hash] = &AssetState{Name: name}
+	s.state.recordAsset(hash, assetPath)
+	s.state.Sources[sourceKey] = hash
+	return name, nil
+}
+
+// downloadAsset streams an attachment to a temporary file of dir, hashing it on the way.
+// Attachments can be large videos, so they are never held in memory.
+func (s *Syncer) downloadAsset(assetURL string, dir string) (string, string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", err
+	}
+
+	resp, err := http.Get(assetURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("bad status: %s", resp.Status)
+	}
+
+	file, err := os.CreateTemp(dir, ".download-*")
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(file, hasher), resp.Body); err != nil {
+		os.Remove(file.Name())
+		return "", "", err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), file.Name(), nil
+}
+
+// processAssets downloads the Notion-hosted attachments and links of the markdown content into the
+// page bundle, and renders the attachment blocks with the asset templates. Attachments always stay in
+// the page bundle, s3_images only applies to images.
+func (s *Syncer) processAssets(markdown string, page PageLocation, pageTitle string) string {
+	baseURI := strings.TrimRight(viper.GetString("posts_base_uri"), "/")
+
+	templates, err := assetTemplates()
+	if err != nil {
+		s.addResult(SyncResult{
+			PageTitle:   pageTitle,
+			Status:      "Warning",
+			Path:        page.Dir,
+			LastUpdated: time.Now(),
+			Message:     err.Error(),
+		})
+	}
+
+	rewrite := func(match string, text string, assetURL string, kind string) string {
+
+		src := assetURL
+		if isNotionHosted(assetURL) {
+			name, err := s.resolveAsset(assetURL, page)
+			if err != nil {
+				s.addResult(SyncResult{
+					PageTitle:   pageTitle,
+					Status:      "Warning",
+					Path:        originalAssetName(assetURL),
+					LastUpdated: time.Now(),
+					Message:     fmt.Sprintf("failed to download attachment, the link will expire: %v", err),
+				})
+			} else {
+				src = fmt.Sprintf("%s/%s/files/%s", baseURI, page.Slug, name)
+			}
+		}
+
+		// Plain links only get their URL rewritten
+		if kind == "" {
+			if src == assetURL {
+				return match
+			}
+			return fmt.Sprintf("[%s](%s)", text, src)
+		}
+
+		var rendered strings.Builder
+		info := AssetInfo{
+			Kind:    kind,
+			Src:     src,
+			Name:    originalAssetName(assetURL),
+			Caption: text,
+		}
+		if err := templates[kind].Execute(&rendered, info); err != nil {
+			s.addResult(SyncResult{
+				PageTitle:   pageTitle,
+				Status:      "Warning",
+				Path:        src,
+				LastUpdated: time.Now(),
+				Message:     fmt.Sprintf("failed to render %s template: %v", kind, err),
+			})
+			return fmt.Sprintf("[%s](%s)", text, src)
+		}
+
+		return "\n" + rendered.String() + "\n"
+	}
+
+	code := codeRanges(markdown)
+
+	var out strings.Builder
+	last := 0
+	for _, loc := range assetRegex.FindAllStringSubmatchIndex(markdown, -1) {
+		// Images are handled by processImages
+		if loc[0] > 0 && markdown[loc[0]-1] == '!' {
+			continue
+		}
+		// Links written in code are shown as is
+		if inRanges(code, loc[0]) {
+			continue
+		}
+
+		group := func(i int) string {
+			if loc[2*i] < 0 {
+				return ""
+			}
+			return markdown[loc[2*i]:loc[2*i+1]]
+		}
+
+		out.WriteString(markdown[last:loc[0]])
+		out.WriteString(rewrite(group(0), group(1), group(2), group(3)))
+		last = loc[1]
+	}
+	out.WriteString(markdown[last:])
+
+	return out.String()
+}
+
+// codeRanges returns the byte ranges of the fenced code blocks and inline code spans of markdown
+func codeRanges(markdown string) [][2]int {
+	var ranges [][2]int
+
+	fence := ""
+	fenceStart := 0
+	for offset := 0; offset < len(markdown); {
+		end := strings.IndexByte(markdown[offset:], '\n')
+		if end < 0 {
+			end = len(markdown)
+		} else {
+			end += offset + 1
+		}
+		line := strings.TrimLeft(markdown[offset:end], " ")
+
+		if fence != "" {
+			// A fence is closed by a line of at least as many of its characters, and nothing else
+			if marker := fenceMarker(line); marker != "" && marker[0] == fence[0] && len(marker) >= len(fence) && strings.TrimSpace(line[len(marker):]) == "" {
+				ranges = append(ranges, [2]int{fenceStart, end})
+				fence = ""
+			}
+		} else if marker := fenceMarker(line); marker != "" && len(markdown[offset:end])-len(line) < 4 {
+			fence = marker
+			fenceStart = offset
+		} else {
+			ranges = append(ranges, inlineCodeRanges(markdown[offset:end], offset)...)
+		}
+
+		offset = end
+	}
+
+	// An unclosed fence runs to the end of the document
+	if fence != "" {
+		ranges = append(ranges, [2]int{fenceStart, len(markdown)})
+	}
+
+	return ranges
+}
+
+// fenceMarker returns the run of backticks or tildes opening a code fence at the start of line, if any
+func fenceMarker(line string) string {
+	if line == "" || (line[0] != '`' && line[0] != '~') {
+		return ""
+	}
+
+	n := 0
+	for n < len(line) && line[n] == line[0] {
+		n++
+	}
+	if n < 3 {
+		return ""
+	}
+
+	return line[:n]
+}
+
+// inlineCodeRanges returns the ranges of the code spans of a line, delimited by runs of backticks
+// of the same length, shifted by offset
+func inlineCodeRanges(line string, offset int) [][2]int {
+	var ranges [][2]int
+
+	for i := 0; i < len(line); {
+		if line[i] != '`' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(line) && line[i] == '`' {
+			i++
+		}
+		delimiter := line[start:i]
+
+		// Look for a closing run of exactly the same length
+		for j := i; j < len(line); {
+			if line[j] != '`' {
+				j++
+				continue
+			}
+			k := j
+			for k < len(line) && line[k] == '`' {
+				k++
+			}
+			if k-j == len(delimiter) {
+				ranges = append(ranges, [2]int{offset + start, offset + k})
+				i = k
+				break
+			}
+			j = k
+		}
+	}
+
+	return ranges
+}
+
+// inRanges reports whether pos falls in one of the ranges
+func inRanges(ranges [][2]int, pos int) bool {
+	for _, r := range ranges {
+		if pos >= r[0] && pos < r[1] {
+			return true
+		}
+	}
+
+	return false
+}
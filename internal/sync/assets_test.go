@@ -0,0 +1,77 @@
+package sync
+
+import "testing"
+
+func TestProcessAssets(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{
+			name:     "plain link",
+			markdown: "see [docs](https://example.com/docs)",
+			want:     "see [docs](https://example.com/docs)",
+		},
+		{
+			name:     "attachment",
+			markdown: `[report](https://example.com/report.pdf "pdf") attached`,
+			want:     "\n[report](https://example.com/report.pdf)\n attached",
+		},
+		{
+			name:     "back-to-back attachments",
+			markdown: `[a](https://example.com/a.zip "file")[b](https://example.com/b.zip "file")`,
+			want:     "\n[a](https://example.com/a.zip)\n\n[b](https://example.com/b.zip)\n",
+		},
+		{
+			name:     "image",
+			markdown: `![photo](https://example.com/photo.png "file")`,
+			want:     `![photo](https://example.com/photo.png "file")`,
+		},
+		{
+			name:     "attachment after an image",
+			markdown: `![photo](https://example.com/photo.png)[a](https://example.com/a.zip "file")`,
+			want:     "![photo](https://example.com/photo.png)\n[a](https://example.com/a.zip)\n",
+		},
+		{
+			name:     "fenced code block",
+			markdown: "```md\n[a](https://example.com/a.zip \"file\")\n```\n",
+			want:     "```md\n[a](https://example.com/a.zip \"file\")\n```\n",
+		},
+		{
+			name:     "tilde fence",
+			markdown: "~~~\n[a](https://example.com/a.zip \"file\")\n~~~",
+			want:     "~~~\n[a](https://example.com/a.zip \"file\")\n~~~",
+		},
+		{
+			name:     "unclosed fence",
+			markdown: "```\n[a](https://example.com/a.zip \"file\")",
+			want:     "```\n[a](https://example.com/a.zip \"file\")",
+		},
+		{
+			name:     "inline code",
+			markdown: "use `[a](https://example.com/a.zip \"file\")` to attach",
+			want:     "use `[a](https://example.com/a.zip \"file\")` to attach",
+		},
+		{
+			name:     "after a code block",
+			markdown: "```\ncode\n```\n[a](https://example.com/a.zip \"file\")",
+			want:     "```\ncode\n```\n\n[a](https://example.com/a.zip)\n",
+		},
+		{
+			name:     "unmatched backtick",
+			markdown: "a ` before [a](https://example.com/a.zip \"file\")",
+			want:     "a ` before \n[a](https://example.com/a.zip)\n",
+		},
+	}
+
+	s := &Syncer{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.processAssets(tt.markdown, PageLocation{Dir: t.TempDir(), Slug: "post"}, "Post")
+			if got != tt.want {
+				t.Errorf("processAssets() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
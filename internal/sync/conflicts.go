@@ -0,0 +1,80 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Suffix of the file holding the Notion version of a post in conflict, written next to the post
+const conflictFileSuffix = ".notion"
+
+// isLocallyModified reports whether a post was edited since the last sync wrote it
+func (st *State) isLocallyModified(pageID string, path string, content []byte) bool {
+	page, ok := st.Pages[NormalizePageID(pageID)]
+	return ok && page.Path == path && !page.matches(content)
+}
+
+// hasNewSource reports whether the post generated from Notion changed since the last sync
+func (st *State) hasNewSource(pageID string, content []byte) bool {
+	page, ok := st.Pages[NormalizePageID(pageID)]
+	return !ok || page.SourceHash != postHash(content)
+}
+
+// resolveConflict handles a post edited locally, depending on the conflicts setting ("keep",
+// "overwrite" or "side-by-side"). Posts edited locally are always reported as conflicts, even without
+// changes in Notion. It returns true when the post must be overwritten, leaving the result to the
+// caller.
+func (s *Syncer) resolveConflict(entry pageEntry, path string, existingContent []byte, newContent []byte) bool {
+	policy := viper.GetString("conflicts")
+	if policy == "overwrite" {
+		return true
+	}
+
+	result := SyncResult{
+		PageTitle:   entry.Title,
+		Status:      "Conflict",
+		Path:        path,
+		LastUpdated: entry.LastEditedTime,
+		Message:     "edited locally, without changes in Notion: the local version was kept",
+		Previous:    string(existingContent),
+		Incoming:    string(newContent),
+	}
+
+	// The Notion version is only written next to the post when the page changed in Notion
+	if !s.state.hasNewSource(entry.ID, newContent) {
+		s.addResult(result)
+		return false
+	}
+
+	result.Message = "edited both locally and in Notion: the local version was kept"
+	if policy == "side-by-side" {
+		notionPath := path + conflictFileSuffix
+		if err := os.WriteFile(notionPath, newContent, 0644); err != nil {
+			s.addResult(SyncResult{
+				PageTitle:   entry.Title,
+				Status:      "Error",
+				Path:        notionPath,
+				LastUpdated: time.Now(),
+				Message:     fmt.Sprintf("failed to write the Notion version of the post: %v", err),
+			})
+			return false
+		}
+
+		// The Notion version is only written again when the page changes
+		s.state.Pages[NormalizePageID(entry.ID)].SourceHash = postHash(newContent)
+		result.Path = notionPath
+		result.Incoming = ""
+		result.Message = "edited both locally and in Notion: the Notion version was written next to the post"
+	}
+
+	s.addResult(result)
+	return false
+}
+
+// removeConflictFile deletes the Notion version of a post once the post is in sync again
+func removeConflictFile(path string) {
+	os.Remove(path + conflictFileSuffix)
+}
@@ -0,0 +1,125 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestResolveConflict(t *testing.T) {
+	const (
+		synced = "synced from Notion"
+		local  = "edited locally"
+		notion = "edited in Notion"
+	)
+
+	tests := []struct {
+		name          string
+		policy        string
+		newContent    string
+		wantOverwrite bool
+		wantResult    bool
+		wantPath      string
+		wantIncoming  string
+	}{
+		{
+			name:         "keep, without changes in Notion",
+			policy:       "keep",
+			newContent:   synced,
+			wantResult:   true,
+			wantIncoming: synced,
+		},
+		{
+			name:         "keep, edited in Notion",
+			policy:       "keep",
+			newContent:   notion,
+			wantResult:   true,
+			wantIncoming: notion,
+		},
+		{
+			name:         "side-by-side, without changes in Notion",
+			policy:       "side-by-side",
+			newContent:   synced,
+			wantResult:   true,
+			wantIncoming: synced,
+		},
+		{
+			name:       "side-by-side, edited in Notion",
+			policy:     "side-by-side",
+			newContent: notion,
+			wantResult: true,
+			wantPath:   conflictFileSuffix,
+		},
+		{
+			name:          "overwrite, without changes in Notion",
+			policy:        "overwrite",
+			newContent:    synced,
+			wantOverwrite: true,
+		},
+		{
+			name:          "overwrite, edited in Notion",
+			policy:        "overwrite",
+			newContent:    notion,
+			wantOverwrite: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.Set("conflicts", tt.policy)
+
+			path := filepath.Join(t.TempDir(), "post.md")
+			if err := os.WriteFile(path, []byte(local), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			entry := pageEntry{ID: "11111111111111111111111111111111", Title: "Post"}
+			s := &Syncer{state: &State{Pages: map[string]*PageState{}}}
+			s.state.recordPage(entry, path, []byte(synced))
+
+			if got := s.resolveConflict(entry, path, []byte(local), []byte(tt.newContent)); got != tt.wantOverwrite {
+				t.Errorf("resolveConflict() = %v, want %v", got, tt.wantOverwrite)
+			}
+
+			// Overwritten posts are reported by the caller, once written
+			if !tt.wantResult {
+				if len(s.results) != 0 {
+					t.Errorf("got results %v, want none", s.results)
+				}
+				return
+			}
+
+			if len(s.results) != 1 {
+				t.Fatalf("got %d results, want 1", len(s.results))
+			}
+			result := s.results[0]
+			if result.Status != "Conflict" {
+				t.Errorf("status = %q, want Conflict", result.Status)
+			}
+			if result.Path != path+tt.wantPath {
+				t.Errorf("path = %q, want %q", result.Path, path+tt.wantPath)
+			}
+			if result.Previous != local {
+				t.Errorf("previous = %q, want the local version", result.Previous)
+			}
+			if result.Incoming != tt.wantIncoming {
+				t.Errorf("incoming = %q, want %q", result.Incoming, tt.wantIncoming)
+			}
+
+			content, err := os.ReadFile(result.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantPath != "" && string(content) != tt.newContent {
+				t.Errorf("%s = %q, want the Notion version", result.Path, content)
+			}
+			if tt.wantPath == "" && string(content) != local {
+				t.Errorf("post = %q, want the local version", content)
+			}
+		})
+	}
+}
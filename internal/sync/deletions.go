@@ -0,0 +1,85 @@
+package sync
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// parseMaxDeletions reads the max_deletions setting, a number of posts or a percentage of the
+// existing posts such as "25%", into the number of posts a sync may delete. It returns -1 when
+// there is no limit. Percentages are rounded down, so the share of posts deleted never goes above
+// them: at 50%, 1 of 2 posts may be deleted, but not the only post of a site.
+func parseMaxDeletions(value string, existing int) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return -1, nil
+	}
+
+	if percent, ok := strings.CutSuffix(value, "%"); ok {
+		ratio, err := strconv.ParseFloat(strings.TrimSpace(percent), 64)
+		if err != nil || ratio < 0 {
+			return 0, fmt.Errorf("invalid max_deletions %q", value)
+		}
+		return int(math.Floor(float64(existing) * ratio / 100)), nil
+	}
+
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		return 0, fmt.Errorf("invalid max_deletions %q", value)
+	}
+
+	return count, nil
+}
+
+// cleanupDirectories deletes the posts of the pages no longer synced, unless there are more than
+// max_deletions of them and force_delete is not set. A Notion API returning a short list of pages
+// must not wipe the site.
+func (s *Syncer) cleanupDirectories(dirPaths []string, existing int) {
+	// Posts already drafted are left as they are, they do not count
+	var pending []string
+	for _, dirPath := range dirPaths {
+		if viper.GetString("delete_policy") == "draft" && isDraft(dirPath) {
+			continue
+		}
+		pending = append(pending, dirPath)
+	}
+
+	if len(pending) == 0 || viper.GetBool("force_delete") {
+		s.deleteDirectories(pending)
+		return
+	}
+
+	maxDeletions, err := parseMaxDeletions(viper.GetString("max_deletions"), existing)
+	if err == nil && (maxDeletions < 0 || len(pending) <= maxDeletions) {
+		s.deleteDirectories(pending)
+		return
+	}
+
+	message := fmt.Sprintf("%d of the %d posts would be deleted, more than max_deletions (%s): run with --force-delete to delete them", len(pending), existing, viper.GetString("max_deletions"))
+	if err != nil {
+		message = fmt.Sprintf("%v: run with --force-delete to delete the %d posts", err, len(pending))
+	}
+	s.addResult(SyncResult{
+		PageTitle:   "Cleanup",
+		Status:      "Warning",
+		Path:        s.contentDir,
+		LastUpdated: time.Now(),
+		Message:     message,
+	})
+
+	for _, dirPath := range pending {
+		s.addResult(SyncResult{
+			PageTitle:   filepath.Base(dirPath),
+			Status:      "Skipped",
+			Path:        dirPath,
+			LastUpdated: time.Now(),
+			Message:     "not deleted, above max_deletions",
+		})
+	}
+}
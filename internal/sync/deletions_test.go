@@ -0,0 +1,38 @@
+package sync
+
+import "testing"
+
+func TestParseMaxDeletions(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		existing int
+		want     int
+		wantErr  bool
+	}{
+		{name: "no limit", value: "", existing: 10, want: -1},
+		{name: "count", value: " 3 ", existing: 10, want: 3},
+		{name: "half of one post", value: "50%", existing: 1, want: 0},
+		{name: "half of two posts", value: "50%", existing: 2, want: 1},
+		{name: "half of three posts", value: "50%", existing: 3, want: 1},
+		{name: "all posts", value: "100%", existing: 1, want: 1},
+		{name: "fractional percentage", value: "33.4%", existing: 3, want: 1},
+		{name: "no post", value: "50%", existing: 0, want: 0},
+		{name: "invalid count", value: "ten", wantErr: true},
+		{name: "negative count", value: "-1", wantErr: true},
+		{name: "invalid percentage", value: "half%", wantErr: true},
+		{name: "negative percentage", value: "-5%", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMaxDeletions(tt.value, tt.existing)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMaxDeletions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseMaxDeletions(%q, %d) = %d, want %d", tt.value, tt.existing, got, tt.want)
+			}
+		})
+	}
+}
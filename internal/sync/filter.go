@@ -0,0 +1,185 @@
+package sync
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jomei/notionapi"
+	"github.com/spf13/viper"
+)
+
+// Regular expression to find the ID at the end of a Notion URL or identifier
+var pageIDRegex = regexp.MustCompile(`[0-9a-f]{32}$`)
+
+// FilterRule matches pages by title, ID or property. The fields set on a rule must all match.
+type FilterRule struct {
+	// Title is a case-insensitive glob, or a regular expression when prefixed with "re:"
+	Title string `mapstructure:"title"`
+	// ID is a page or database ID, or its URL. Database IDs match all the rows of the database.
+	ID string `mapstructure:"id"`
+	// Property is the name of a property of database rows, which must be set or equal to Value
+	Property string `mapstructure:"property"`
+	Value    string `mapstructure:"value"`
+
+	titleRegex *regexp.Regexp
+}
+
+// PageFilter tells which pages to sync, from the include and exclude rules of the configuration.
+// Pages are synced when they match one of the include rules, or when there are none, and none of
+// the exclude rules.
+type PageFilter struct {
+	Include []FilterRule
+	Exclude []FilterRule
+}
+
+// PageInfo is what the filters know about a page
+type PageInfo struct {
+	ID    string
+	Title string
+	// ParentID is the ID of the database of a row
+	ParentID string
+	// Properties are only known for database rows
+	Properties notionapi.Properties
+}
+
+// NormalizePageID returns a page ID without dashes, as found in Notion URLs, so IDs coming from the
+// API, URLs and the configuration can be compared
+func NormalizePageID(id string) string {
+	id, _, _ = strings.Cut(id, "?")
+	id, _, _ = strings.Cut(id, "#")
+	id = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(id), "-", ""))
+
+	if match := pageIDRegex.FindString(id); match != "" {
+		return match
+	}
+
+	return id
+}
+
+// LoadPageFilter reads the include and exclude rules of the configuration
+func LoadPageFilter() (*PageFilter, error) {
+	filter := &PageFilter{}
+
+	for key, rules := range map[string]*[]FilterRule{"include": &filter.Include, "exclude": &filter.Exclude} {
+		if err := viper.UnmarshalKey(key, rules); err != nil {
+			return nil, fmt.Errorf("invalid %s rules: %v", key, err)
+		}
+
+		for i := range *rules {
+			rule := &(*rules)[i]
+			if rule.Title == "" && rule.ID == "" && rule.Property == "" {
+				return nil, fmt.Errorf("invalid %s rule #%d: title, id or property is required", key, i+1)
+			}
+
+			if pattern, ok := strings.CutPrefix(rule.Title, "re:"); ok {
+				titleRegex, err := regexp.Compile(pattern)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s rule #%d: %v", key, i+1, err)
+				}
+				rule.titleRegex = titleRegex
+			} else if _, err := path.Match(rule.Title, ""); err != nil {
+				return nil, fmt.Errorf("invalid %s rule #%d: %v", key, i+1, err)
+			}
+
+			rule.ID = NormalizePageID(rule.ID)
+		}
+	}
+
+	return filter, nil
+}
+
+// Allows reports whether a page must be synced
+func (f *PageFilter) Allows(page PageInfo) bool {
+	if f == nil {
+		return true
+	}
+
+	if len(f.Include) > 0 && !slices.ContainsFunc(f.Include, page.matches) {
+		return false
+	}
+
+	return !f.Excludes(page)
+}
+
+// Excludes reports whether a page matches one of the exclude rules. Databases are only checked
+// against the exclude rules, their rows being included one by one.
+func (f *PageFilter) Excludes(page PageInfo) bool {
+	if f == nil {
+		return false
+	}
+
+	return slices.ContainsFunc(f.Exclude, page.matches)
+}
+
+// matches reports whether the page matches all the fields of a rule
+func (page PageInfo) matches(rule FilterRule) bool {
+	if rule.ID != "" {
+		if rule.ID != NormalizePageID(page.ID) && (page.ParentID == "" || rule.ID != NormalizePageID(page.ParentID)) {
+			return false
+		}
+	}
+
+	if rule.titleRegex != nil {
+		if !rule.titleRegex.MatchString(page.Title) {
+			return false
+		}
+	} else if rule.Title != "" {
+		if ok, _ := path.Match(strings.ToLower(rule.Title), strings.ToLower(page.Title)); !ok {
+			return false
+		}
+	}
+
+	if rule.Property != "" {
+		values, ok := propertyValues(page.Properties[rule.Property])
+		if !ok {
+			return false
+		}
+
+		if rule.Value == "" {
+			return slices.ContainsFunc(values, func(value string) bool { return value != "" })
+		}
+
+		return slices.ContainsFunc(values, func(value string) bool {
+			return strings.EqualFold(strings.TrimSpace(rule.Value), value)
+		})
+	}
+
+	return true
+}
+
+// propertyValues returns the values of a property as text. Multi-selects have a value per option
+// and checkboxes read as "true" or "false".
+func propertyValues(property notionapi.Property) ([]string, bool) {
+	switch p := property.(type) {
+	case *notionapi.TitleProperty:
+		return []string{richTextValue(p.Title)}, true
+	case *notionapi.RichTextProperty:
+		return []string{richTextValue(p.RichText)}, true
+	case *notionapi.StatusProperty:
+		return []string{p.Status.Name}, true
+	case *notionapi.SelectProperty:
+		return []string{p.Select.Name}, true
+	case *notionapi.MultiSelectProperty:
+		var values []string
+		for _, option := range p.MultiSelect {
+			values = append(values, option.Name)
+		}
+		return values, true
+	case *notionapi.CheckboxProperty:
+		return []string{strconv.FormatBool(p.Checkbox)}, true
+	case *notionapi.NumberProperty:
+		return []string{strconv.FormatFloat(p.Number, 'f', -1, 64)}, true
+	case *notionapi.URLProperty:
+		return []string{p.URL}, true
+	case *notionapi.EmailProperty:
+		return []string{p.Email}, true
+	case *notionapi.PhoneNumberProperty:
+		return []string{p.PhoneNumber}, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,218 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/jomei/notionapi"
+	"github.com/spf13/viper"
+)
+
+func TestNormalizePageID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want string
+	}{
+		{
+			name: "API ID",
+			id:   "01234567-89ab-cdef-0123-456789abcdef",
+			want: "0123456789abcdef0123456789abcdef",
+		},
+		{
+			name: "ID without dashes",
+			id:   " 0123456789ABCDEF0123456789ABCDEF ",
+			want: "0123456789abcdef0123456789abcdef",
+		},
+		{
+			name: "page URL",
+			id:   "https://www.notion.so/workspace/My-Post-0123456789abcdef0123456789abcdef?pvs=4#heading",
+			want: "0123456789abcdef0123456789abcdef",
+		},
+		{
+			name: "not an ID",
+			id:   "My-Post",
+			want: "mypost",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizePageID(tt.id); got != tt.want {
+				t.Errorf("NormalizePageID(%q) = %q, want %q", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPageFilterErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []map[string]string
+		exclude []map[string]string
+	}{
+		{
+			name:    "empty rule",
+			include: []map[string]string{{"value": "blog"}},
+		},
+		{
+			name:    "invalid regular expression",
+			exclude: []map[string]string{{"title": "re:("}},
+		},
+		{
+			name:    "invalid glob",
+			include: []map[string]string{{"title": "[a-"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.Set("include", tt.include)
+			viper.Set("exclude", tt.exclude)
+
+			if _, err := LoadPageFilter(); err == nil {
+				t.Error("LoadPageFilter() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestPageFilterAllows(t *testing.T) {
+	const (
+		pageID     = "11111111-1111-1111-1111-111111111111"
+		databaseID = "22222222-2222-2222-2222-222222222222"
+	)
+
+	// A database row, with its properties as returned by the API
+	row := PageInfo{
+		ID:       "33333333-3333-3333-3333-333333333333",
+		Title:    "Week 12",
+		ParentID: databaseID,
+		Properties: notionapi.Properties{
+			"Name":      &notionapi.TitleProperty{Title: []notionapi.RichText{{PlainText: "Week 12"}}},
+			"Tags":      &notionapi.MultiSelectProperty{MultiSelect: []notionapi.Option{{Name: "Go"}, {Name: "Blog"}}},
+			"Featured":  &notionapi.CheckboxProperty{Checkbox: true},
+			"Summary":   &notionapi.RichTextProperty{},
+			"Published": &notionapi.DateProperty{},
+		},
+	}
+	page := PageInfo{ID: pageID, Title: "Scratch notes"}
+
+	tests := []struct {
+		name    string
+		include []map[string]string
+		exclude []map[string]string
+		page    PageInfo
+		want    bool
+	}{
+		{
+			name: "no rules",
+			page: page,
+			want: true,
+		},
+		{
+			name:    "glob title",
+			include: []map[string]string{{"title": "SCRATCH*"}},
+			page:    page,
+			want:    true,
+		},
+		{
+			name:    "glob title not matching",
+			include: []map[string]string{{"title": "scratch"}},
+			page:    page,
+			want:    false,
+		},
+		{
+			name:    "regular expression title",
+			include: []map[string]string{{"title": `re:^Week \d+$`}},
+			page:    row,
+			want:    true,
+		},
+		{
+			name:    "regular expression is case-sensitive",
+			include: []map[string]string{{"title": `re:^week`}},
+			page:    row,
+			want:    false,
+		},
+		{
+			name:    "page URL",
+			exclude: []map[string]string{{"id": "https://www.notion.so/Scratch-11111111111111111111111111111111"}},
+			page:    page,
+			want:    false,
+		},
+		{
+			name:    "database ID matches its rows",
+			exclude: []map[string]string{{"id": databaseID}},
+			page:    row,
+			want:    false,
+		},
+		{
+			name:    "multi-select value",
+			include: []map[string]string{{"property": "Tags", "value": "blog"}},
+			page:    row,
+			want:    true,
+		},
+		{
+			name:    "checkbox value",
+			include: []map[string]string{{"property": "Featured", "value": "false"}},
+			page:    row,
+			want:    false,
+		},
+		{
+			name:    "empty property",
+			include: []map[string]string{{"property": "Summary"}},
+			page:    row,
+			want:    false,
+		},
+		{
+			name:    "unsupported property type",
+			include: []map[string]string{{"property": "Published"}},
+			page:    row,
+			want:    false,
+		},
+		{
+			name:    "property of a page outside of a database",
+			include: []map[string]string{{"property": "Tags", "value": "blog"}},
+			page:    page,
+			want:    false,
+		},
+		{
+			name:    "all the fields of a rule must match",
+			include: []map[string]string{{"title": "week*", "property": "Tags", "value": "rust"}},
+			page:    row,
+			want:    false,
+		},
+		{
+			name:    "one of the include rules",
+			include: []map[string]string{{"title": "scratch*"}, {"property": "Tags", "value": "go"}},
+			page:    row,
+			want:    true,
+		},
+		{
+			name:    "exclude wins over include",
+			include: []map[string]string{{"property": "Featured"}},
+			exclude: []map[string]string{{"title": "week*"}},
+			page:    row,
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.Set("include", tt.include)
+			viper.Set("exclude", tt.exclude)
+
+			filter, err := LoadPageFilter()
+			if err != nil {
+				t.Fatalf("LoadPageFilter() error = %v", err)
+			}
+
+			if got := filter.Allows(tt.page); got != tt.want {
+				t.Errorf("Allows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
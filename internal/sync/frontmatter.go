@@ -0,0 +1,418 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// defaultFrontMatterMapping lists the front matter fields set by hugo-notion and the key each one
+// is written to. Nested keys are written with dots, e.g. "cover.image" for PaperMod covers.
+var defaultFrontMatterMapping = map[string]string{
+	"title":       "title",
+	"type":        "type",
+	"date":        "date",
+	"lastmod":     "lastmod",
+	"draft":       "draft",
+	"publishDate": "publishDate",
+	"expiryDate":  "expiryDate",
+	"cover":       "cover.image",
+	"images":      "images",
+	"icon":        "icon",
+	"imagesMeta":  "imagesMeta",
+}
+
+// frontMatterMapping returns the front matter mapping, front_matter_mapping overriding the defaults.
+// A field mapped to an empty key is left out of the front matter.
+func frontMatterMapping() map[string]string {
+	mapping := make(map[string]string)
+	for field, key := range defaultFrontMatterMapping {
+		mapping[field] = key
+	}
+
+	for field, key := range viper.GetStringMapString("front_matter_mapping") {
+		mapping[field] = key
+	}
+
+	return mapping
+}
+
+// buildFrontMatter maps the fields of a page to their front matter keys
+func buildFrontMatter(fields map[string]interface{}) map[string]interface{} {
+	mapping := frontMatterMapping()
+	frontMatter := make(map[string]interface{})
+
+	for field, value := range fields {
+		key, ok := mapping[field]
+		if !ok || key == "" {
+			continue
+		}
+		setFrontMatterKey(frontMatter, key, value)
+	}
+
+	return frontMatter
+}
+
+// setFrontMatterKey sets a possibly nested key, creating the intermediate maps as needed
+func setFrontMatterKey(frontMatter map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	current := frontMatter
+
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := current[part].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			current[part] = next
+		}
+		current = next
+	}
+
+	current[parts[len(parts)-1]] = value
+}
+
+// managedFrontMatterKeys returns the front matter keys written by hugo-notion, whether the page sets
+// them or not
+func managedFrontMatterKeys() []string {
+	var keys []string
+	for _, key := range frontMatterMapping() {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// getFrontMatterKey returns the value of a possibly nested key
+func getFrontMatterKey(frontMatter map[string]interface{}, key string) (interface{}, bool) {
+	parts := strings.Split(key, ".")
+	current := frontMatter
+
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := current[part].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+
+	value, ok := current[parts[len(parts)-1]]
+	return value, ok
+}
+
+// splitFrontMatter separates the YAML front matter of a post from its content
+func splitFrontMatter(content []byte) ([]byte, []byte, bool) {
+	rest, ok := bytes.CutPrefix(content, []byte("---\n"))
+	if !ok {
+		return nil, content, false
+	}
+
+	frontMatter, body, ok := bytes.Cut(rest, []byte("\n---\n"))
+	if !ok {
+		return nil, content, false
+	}
+
+	return frontMatter, body, true
+}
+
+// parseFrontMatter reads the YAML front matter of a post
+func parseFrontMatter(content []byte) (map[string]interface{}, []byte, bool) {
+	rawFrontMatter, body, ok := splitFrontMatter(content)
+	if !ok {
+		return nil, content, false
+	}
+
+	var frontMatter map[string]interface{}
+	if err := yaml.Unmarshal(rawFrontMatter, &frontMatter); err != nil {
+		return nil, content, false
+	}
+	if frontMatter == nil {
+		frontMatter = make(map[string]interface{})
+	}
+
+	return stringKeys(frontMatter).(map[string]interface{}), body, true
+}
+
+// stringKeys turns the nested maps decoded from YAML, keyed by interface{}, into maps keyed by
+// strings like the ones of the generated front matter
+func stringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = stringKeys(item)
+		}
+		return m
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = stringKeys(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = stringKeys(item)
+		}
+		return v
+	}
+
+	return value
+}
+
+// mergeFrontMatter keeps the keys of the existing front matter of a post that hugo-notion does not
+// manage, such as fields added by hand, and replaces the managed ones with the generated front matter.
+// The YAML nodes are edited in place, so the order of the keys and the comments are kept. It returns
+// false when the existing front matter cannot be read.
+func mergeFrontMatter(existingContent []byte, frontMatter map[string]interface{}) ([]byte, bool) {
+	rawFrontMatter, _, ok := splitFrontMatter(existingContent)
+	if !ok {
+		return nil, false
+	}
+
+	var document yamlv3.Node
+	if err := yamlv3.Unmarshal(rawFrontMatter, &document); err != nil {
+		return nil, false
+	}
+	if document.Kind == 0 {
+		document = yamlv3.Node{Kind: yamlv3.DocumentNode, Content: []*yamlv3.Node{{Kind: yamlv3.MappingNode, Tag: "!!map"}}}
+	}
+	if document.Kind != yamlv3.DocumentNode || len(document.Content) != 1 || document.Content[0].Kind != yamlv3.MappingNode {
+		return nil, false
+	}
+	root := document.Content[0]
+
+	// Sorted, for the keys added to the end of the front matter to always come in the same order
+	keys := managedFrontMatterKeys()
+	slices.Sort(keys)
+	for _, key := range keys {
+		value, ok := getFrontMatterKey(frontMatter, key)
+		if !ok {
+			deleteNodeKey(root, key)
+			continue
+		}
+
+		var valueNode yamlv3.Node
+		if err := valueNode.Encode(value); err != nil {
+			return nil, false
+		}
+		setNodeKey(root, key, &valueNode)
+	}
+
+	var merged bytes.Buffer
+	encoder := yamlv3.NewEncoder(&merged)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return nil, false
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, false
+	}
+
+	return merged.Bytes(), true
+}
+
+// nodeKeyIndex returns the index of the value of a key in a mapping node
+func nodeKeyIndex(mapping *yamlv3.Node, key string) (int, bool) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i + 1, true
+		}
+	}
+
+	return 0, false
+}
+
+// setNodeKey sets a possibly nested key of a mapping node. Existing keys keep their place and
+// comments, new ones are added at the end.
+func setNodeKey(mapping *yamlv3.Node, key string, value *yamlv3.Node) {
+	part, rest, nested := strings.Cut(key, ".")
+	i, ok := nodeKeyIndex(mapping, part)
+	if !ok {
+		mapping.Content = append(mapping.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: part}, nil)
+		i = len(mapping.Content) - 1
+	}
+
+	if !nested {
+		if previous := mapping.Content[i]; previous != nil {
+			value.HeadComment, value.LineComment, value.FootComment = previous.HeadComment, previous.LineComment, previous.FootComment
+		}
+		mapping.Content[i] = value
+		return
+	}
+
+	if mapping.Content[i] == nil || mapping.Content[i].Kind != yamlv3.MappingNode {
+		mapping.Content[i] = &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+	}
+	setNodeKey(mapping.Content[i], rest, value)
+}
+
+// deleteNodeKey removes a possibly nested key of a mapping node, along with the mappings it leaves
+// empty
+func deleteNodeKey(mapping *yamlv3.Node, key string) {
+	part, rest, nested := strings.Cut(key, ".")
+	i, ok := nodeKeyIndex(mapping, part)
+	if !ok {
+		return
+	}
+
+	if nested {
+		next := mapping.Content[i]
+		if next.Kind != yamlv3.MappingNode {
+			return
+		}
+		deleteNodeKey(next, rest)
+		if len(next.Content) > 0 {
+			return
+		}
+	}
+
+	mapping.Content = slices.Delete(mapping.Content, i-1, i+1)
+}
+
+// managedContent returns a post with only the front matter keys managed by hugo-notion, to compare
+// posts whatever fields were added by hand
+func managedContent(content []byte) ([]byte, bool) {
+	frontMatter, body, ok := parseFrontMatter(content)
+	if !ok {
+		return nil, false
+	}
+
+	managed := make(map[string]interface{})
+	for _, key := range managedFrontMatterKeys() {
+		if value, ok := getFrontMatterKey(frontMatter, key); ok {
+			setFrontMatterKey(managed, key, value)
+		}
+	}
+
+	managedYaml, err := yaml.Marshal(managed)
+	if err != nil {
+		return nil, false
+	}
+
+	return append(managedYaml, body...), true
+}
+
+// pageDates sets the publication date of a page, taken from the date_property Date property when
+// configured and set, or from its creation time, and its last modification date.
+// The scheduled publishDate and expiryDate come from the publish_date_property and
+// expiry_date_property Date properties.
+func (s *Syncer) pageDates(entry pageEntry, notionPage *notionapi.Page, hugoPageFilePath string, pageTitle string, fields map[string]interface{}) {
+	location := time.UTC
+	if timezone := viper.GetString("timezone"); timezone != "" {
+		loaded, err := time.LoadLocation(timezone)
+		if err == nil {
+			location = loaded
+		} else {
+			s.addResult(SyncResult{
+				PageTitle:   pageTitle,
+				Status:      "Warning",
+				Path:        hugoPageFilePath,
+				LastUpdated: time.Now(),
+				Message:     fmt.Sprintf("unknown timezone, using UTC: %v", err),
+			})
+		}
+	}
+
+	dateFormat := viper.GetString("date_format")
+	if dateFormat == "" {
+		dateFormat = time.RFC3339
+	}
+
+	formatDate := func(date time.Time) string {
+		return date.In(location).Format(dateFormat)
+	}
+
+	if date, ok := pageDateProperty(notionPage, viper.GetString("date_property")); ok {
+		fields["date"] = formatDate(date)
+	} else if !entry.CreatedTime.IsZero() {
+		fields["date"] = formatDate(entry.CreatedTime)
+	}
+
+	if !entry.LastEditedTime.IsZero() {
+		fields["lastmod"] = formatDate(entry.LastEditedTime)
+	}
+
+	if date, ok := pageDateProperty(notionPage, viper.GetString("publish_date_property")); ok {
+		fields["publishDate"] = formatDate(date)
+	}
+
+	if date, ok := pageDateProperty(notionPage, viper.GetString("expiry_date_property")); ok {
+		fields["expiryDate"] = formatDate(date)
+	}
+}
+
+// pageDateProperty returns the start of the named Date property of a page, if it is set
+func pageDateProperty(notionPage *notionapi.Page, name string) (time.Time, bool) {
+	if notionPage == nil || name == "" {
+		return time.Time{}, false
+	}
+
+	property, ok := notionPage.Properties[name].(*notionapi.DateProperty)
+	if !ok || property.Date == nil || property.Date.Start == nil {
+		return time.Time{}, false
+	}
+
+	return time.Time(*property.Date.Start), true
+}
+
+// pageCoverAndIcon downloads the cover and the icon of a page and sets the matching front matter fields.
+// Emoji icons are used as is.
+func (s *Syncer) pageCoverAndIcon(notionPage *notionapi.Page, page PageLocation, pageTitle string, fields map[string]interface{}) {
+	if notionPage.Cover != nil {
+		if coverURL := notionPage.Cover.GetURL(); coverURL != "" {
+			if src, err := s.frontMatterImage(coverURL, page); err == nil {
+				fields["cover"] = src
+				fields["images"] = []string{src}
+			} else {
+				s.addResult(SyncResult{
+					PageTitle:   pageTitle,
+					Status:      "Warning",
+					Path:        page.Dir,
+					LastUpdated: time.Now(),
+					Message:     fmt.Sprintf("failed to download the cover: %v", err),
+				})
+			}
+		}
+	}
+
+	if notionPage.Icon != nil {
+		if notionPage.Icon.Emoji != nil {
+			fields["icon"] = string(*notionPage.Icon.Emoji)
+		} else if iconURL := notionPage.Icon.GetURL(); iconURL != "" {
+			if src, err := s.frontMatterImage(iconURL, page); err == nil {
+				fields["icon"] = src
+			} else {
+				s.addResult(SyncResult{
+					PageTitle:   pageTitle,
+					Status:      "Warning",
+					Path:        page.Dir,
+					LastUpdated: time.Now(),
+					Message:     fmt.Sprintf("failed to download the icon: %v", err),
+				})
+			}
+		}
+	}
+}
+
+// frontMatterImage stores an image referenced from the front matter, like the images of the content,
+// and returns its URL
+func (s *Syncer) frontMatterImage(imageURL string, page PageLocation) (string, error) {
+	hash, err := s.resolveImage(imageURL, page, generateImageFilename(imageURL))
+	if err != nil {
+		return "", err
+	}
+
+	return s.images.URL(page, imageBaseName(hash)+s.state.Images[hash].Ext), nil
+}
+
+// fetchPage retrieves the page object, which holds the properties, cover and icon of a page
+func (s *Syncer) fetchPage(pageID string) (*notionapi.Page, error) {
+	return s.client.Page.Get(context.Background(), notionapi.PageID(pageID))
+}
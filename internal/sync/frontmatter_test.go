@@ -0,0 +1,263 @@
+package sync
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/spf13/viper"
+)
+
+func TestBuildFrontMatter(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping map[string]string
+		fields  map[string]interface{}
+		want    map[string]interface{}
+	}{
+		{
+			name:   "default mapping",
+			fields: map[string]interface{}{"title": "Post", "cover": "images/cover.png", "lastmod": "2024-05-02"},
+			want: map[string]interface{}{
+				"title":   "Post",
+				"cover":   map[string]interface{}{"image": "images/cover.png"},
+				"lastmod": "2024-05-02",
+			},
+		},
+		{
+			name:    "renamed and nested keys",
+			mapping: map[string]string{"cover": "featured_image", "icon": "params.icon"},
+			fields:  map[string]interface{}{"cover": "images/cover.png", "icon": "🐱"},
+			want: map[string]interface{}{
+				"featured_image": "images/cover.png",
+				"params":         map[string]interface{}{"icon": "🐱"},
+			},
+		},
+		{
+			name:    "field left out",
+			mapping: map[string]string{"images": ""},
+			fields:  map[string]interface{}{"title": "Post", "images": []string{"images/cover.png"}},
+			want:    map[string]interface{}{"title": "Post"},
+		},
+		{
+			name:   "unknown field",
+			fields: map[string]interface{}{"unknown": "value"},
+			want:   map[string]interface{}{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.Set("front_matter_mapping", tt.mapping)
+
+			if got := buildFrontMatter(tt.fields); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildFrontMatter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageDates(t *testing.T) {
+	created := time.Date(2024, 5, 1, 22, 30, 0, 0, time.UTC)
+	edited := time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC)
+	published := notionapi.Date(time.Date(2023, 12, 31, 23, 0, 0, 0, time.UTC))
+	expires := notionapi.Date(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	// A database row, with its properties as returned by the API
+	row := &notionapi.Page{
+		Properties: notionapi.Properties{
+			"Name":      &notionapi.TitleProperty{Title: []notionapi.RichText{{PlainText: "Post"}}},
+			"Published": &notionapi.DateProperty{Date: &notionapi.DateObject{Start: &published}},
+			"Empty":     &notionapi.DateProperty{},
+			"Expires":   &notionapi.DateProperty{Date: &notionapi.DateObject{Start: &expires}},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		settings     map[string]string
+		page         *notionapi.Page
+		wantDate     string
+		wantLastmod  string
+		wantPublish  string
+		wantExpiry   string
+		wantWarnings int
+	}{
+		{
+			name:        "creation and last edition times",
+			wantDate:    "2024-05-01T22:30:00Z",
+			wantLastmod: "2024-05-02T08:00:00Z",
+		},
+		{
+			name:        "date property",
+			settings:    map[string]string{"date_property": "Published"},
+			page:        row,
+			wantDate:    "2023-12-31T23:00:00Z",
+			wantLastmod: "2024-05-02T08:00:00Z",
+		},
+		{
+			name:        "empty date property",
+			settings:    map[string]string{"date_property": "Empty"},
+			page:        row,
+			wantDate:    "2024-05-01T22:30:00Z",
+			wantLastmod: "2024-05-02T08:00:00Z",
+		},
+		{
+			name:        "date property without the page",
+			settings:    map[string]string{"date_property": "Published"},
+			wantDate:    "2024-05-01T22:30:00Z",
+			wantLastmod: "2024-05-02T08:00:00Z",
+		},
+		{
+			name:        "publish and expiry date properties",
+			settings:    map[string]string{"publish_date_property": "Published", "expiry_date_property": "Expires"},
+			page:        row,
+			wantDate:    "2024-05-01T22:30:00Z",
+			wantLastmod: "2024-05-02T08:00:00Z",
+			wantPublish: "2023-12-31T23:00:00Z",
+			wantExpiry:  "2025-01-01T00:00:00Z",
+		},
+		{
+			name:        "timezone and date format",
+			settings:    map[string]string{"timezone": "Europe/Paris", "date_format": "2006-01-02"},
+			wantDate:    "2024-05-02",
+			wantLastmod: "2024-05-02",
+		},
+		{
+			name:         "unknown timezone",
+			settings:     map[string]string{"timezone": "Mars/Olympus"},
+			wantDate:     "2024-05-01T22:30:00Z",
+			wantLastmod:  "2024-05-02T08:00:00Z",
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			for key, value := range tt.settings {
+				viper.Set(key, value)
+			}
+
+			s := &Syncer{}
+			entry := pageEntry{Title: "Post", CreatedTime: created, LastEditedTime: edited, Page: tt.page}
+			fields := map[string]interface{}{}
+			s.pageDates(entry, tt.page, "post/post.md", entry.Title, fields)
+
+			if fields["date"] != tt.wantDate {
+				t.Errorf("date = %v, want %v", fields["date"], tt.wantDate)
+			}
+			if fields["lastmod"] != tt.wantLastmod {
+				t.Errorf("lastmod = %v, want %v", fields["lastmod"], tt.wantLastmod)
+			}
+			if publishDate, _ := fields["publishDate"].(string); publishDate != tt.wantPublish {
+				t.Errorf("publishDate = %v, want %v", publishDate, tt.wantPublish)
+			}
+			if expiryDate, _ := fields["expiryDate"].(string); expiryDate != tt.wantExpiry {
+				t.Errorf("expiryDate = %v, want %v", expiryDate, tt.wantExpiry)
+			}
+			if len(s.results) != tt.wantWarnings {
+				t.Errorf("got %d results, want %d", len(s.results), tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestMergeFrontMatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		fields   map[string]interface{}
+		want     string
+	}{
+		{
+			name: "order and comments kept",
+			existing: `---
+# Written by hugo-notion
+title: Old title # from Notion
+tags: [go, hugo]
+cover:
+  image: images/old.png
+  alt: A cat
+series: notes
+---
+
+Body
+`,
+			fields: map[string]interface{}{"title": "New title", "cover": "images/new.png"},
+			want: `# Written by hugo-notion
+title: New title # from Notion
+tags: [go, hugo]
+cover:
+  image: images/new.png
+  alt: A cat
+series: notes
+`,
+		},
+		{
+			name: "new keys added at the end in order",
+			existing: `---
+series: notes
+title: Post
+---
+`,
+			fields: map[string]interface{}{"title": "Post", "type": "Post", "date": "2024-05-01"},
+			want: `series: notes
+title: Post
+date: "2024-05-01"
+type: Post
+`,
+		},
+		{
+			name: "managed keys no longer set are removed",
+			existing: `---
+draft: true
+cover:
+  image: images/old.png
+weight: 3
+---
+`,
+			fields: map[string]interface{}{"title": "Post"},
+			want: `weight: 3
+title: Post
+`,
+		},
+		{
+			name: "empty front matter",
+			existing: `---
+
+---
+`,
+			fields: map[string]interface{}{"title": "Post"},
+			want: `title: Post
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+
+			got, ok := mergeFrontMatter([]byte(tt.existing), buildFrontMatter(tt.fields))
+			if !ok {
+				t.Fatal("mergeFrontMatter() failed")
+			}
+			if string(got) != tt.want {
+				t.Errorf("mergeFrontMatter() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeFrontMatterInvalid(t *testing.T) {
+	for _, existing := range []string{"no front matter", "---\n- a list\n---\n", "---\ntitle: [\n---\n"} {
+		if _, ok := mergeFrontMatter([]byte(existing), map[string]interface{}{"title": "Post"}); ok {
+			t.Errorf("mergeFrontMatter(%q) succeeded, want a failure", existing)
+		}
+	}
+}
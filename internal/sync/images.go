@@ -0,0 +1,417 @@
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Regular expression to find Markdown image tags
+var imageRegex = regexp.MustCompile(`!\[(.*?)\]\((.*?)\)`)
+
+// Extensions of the image formats recognized by http.DetectContentType
+var imageExtensions = map[string]string{
+	"image/png":                ".png",
+	"image/jpeg":               ".jpg",
+	"image/gif":                ".gif",
+	"image/webp":               ".webp",
+	"image/bmp":                ".bmp",
+	"image/x-icon":             ".ico",
+	"image/vnd.microsoft.icon": ".ico",
+}
+
+// downloadImage downloads an image from a URL and returns its content
+func (s *Syncer) downloadImage(imageURL string) ([]byte, error) {
+	resp, err := http.Get(imageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bad status: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	s.addImageBytes(len(data))
+	return data, err
+}
+
+// generateImageFilename returns the original filename of an image based on its URL
+func generateImageFilename(imageURL string) string {
+	parsedURL, err := url.Parse(imageURL)
+	if err != nil {
+		// If URL parsing fails, use a hash of the full URL
+		hash := sha256.Sum256([]byte(imageURL))
+		return fmt.Sprintf("%x%s", hash[:8], filepath.Ext(imageURL))
+	}
+
+	// Use the last part of the path as the filename
+	basename := filepath.Base(parsedURL.Path)
+	if basename == "" || basename == "." {
+		// If no filename in URL, use a hash
+		hash := sha256.Sum256([]byte(imageURL))
+		return fmt.Sprintf("%x%s", hash[:8], filepath.Ext(imageURL))
+	}
+
+	return basename
+}
+
+// imageExtension sniffs the extension of an image from its content, falling back on the original filename
+func imageExtension(data []byte, originalName string) string {
+	contentType := http.DetectContentType(data)
+	if ext, ok := imageExtensions[contentType]; ok {
+		return ext
+	}
+
+	// SVG is sniffed as XML or plain text
+	if strings.Contains(string(data[:min(len(data), 512)]), "<svg") {
+		return ".svg"
+	}
+
+	return strings.ToLower(filepath.Ext(originalName))
+}
+
+// imageSourceKey returns the part of an image URL that survives Notion's URL signing.
+// Notion-hosted files live under a stable "/<workspace>/<file uuid>/<name>" path while the
+// query string carries short-lived credentials, so only the host and path are kept. The query of
+// other URLs may select the image, e.g. a generated chart, so they are kept whole.
+func imageSourceKey(imageURL string) string {
+	if !isNotionHosted(imageURL) {
+		return imageURL
+	}
+
+	parsedURL, err := url.Parse(imageURL)
+	if err != nil {
+		return imageURL
+	}
+
+	return parsedURL.Host + parsedURL.Path
+}
+
+// imageBaseName returns the name of an image stored by content hash, without extension
+func imageBaseName(hash string) string {
+	return hash[:16]
+}
+
+// imageFileNames lists the names of the files of a stored image: the image itself then its variants
+func imageFileNames(hash string, image *ImageState) []string {
+	baseName := imageBaseName(hash)
+	names := []string{baseName + image.Ext}
+	for _, variant := range image.Variants {
+		names = append(names, baseName+variant.Suffix)
+	}
+
+	return names
+}
+
+// resolveImage makes the image available to the page and returns its content hash.
+// Images already known from a previous sync are reused without any request to Notion.
+func (s *Syncer) resolveImage(imageURL string, page PageLocation, originalName string) (string, error) {
+	sourceKey := imageSourceKey(imageURL)
+	if hash, ok := s.state.Sources[sourceKey]; ok {
+		if s.reuseImage(hash, page) {
+			return hash, nil
+		}
+	}
+
+	data, err := s.downloadImage(imageURL)
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := s.storeImage(data, page, originalName)
+	if err != nil {
+		return "", err
+	}
+
+	s.state.Sources[sourceKey] = hash
+	return hash, nil
+}
+
+// reuseImage makes an already stored image, and its variants, available to the page.
+// Missing files are copied from a local copy of another page when there is one.
+// Images processed with different settings than the current ones are not reused.
+func (s *Syncer) reuseImage(hash string, page PageLocation) bool {
+	image, ok := s.state.Images[hash]
+	if !ok || image.Profile != imageOptionsFromConfig().profile() {
+		return false
+	}
+
+	for _, name := range imageFileNames(hash, image) {
+		if s.images.Has(page, name) {
+			continue
+		}
+
+		existing, ok := s.state.localCopy(name)
+		if !ok {
+			return false
+		}
+
+		data, err := os.ReadFile(existing)
+		if err != nil {
+			return false
+		}
+
+		if err := s.images.Put(page, name, data); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// storeImage saves the image content under a name derived from its hash, along with its
+// optimized variants. Identical images share the same files.
+func (s *Syncer) storeImage(data []byte, page PageLocation, originalName string) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	if s.reuseImage(hash, page) {
+		return hash, nil
+	}
+
+	opts := imageOptionsFromConfig()
+	image := &ImageState{
+		Ext:     imageExtension(data, originalName),
+		Profile: opts.profile(),
+	}
+
+	var variants []processedVariant
+	if opts.Enabled {
+		// Formats that cannot be optimized, such as SVG or GIF, are kept as is
+		if processed, err := optimizeImage(data, opts); err == nil {
+			data = processed.Data
+			variants = processed.Variants
+			image.WebPDropped = processed.WebPDropped
+		}
+	}
+
+	image.Width, image.Height, image.Placeholder = analyzeImage(data, opts.Placeholder)
+	for _, variant := range variants {
+		image.Variants = append(image.Variants, variant.ImageVariant)
+	}
+	s.state.setImage(hash, image)
+
+	baseName := imageBaseName(hash)
+	if err := s.images.Put(page, baseName+image.Ext, data); err != nil {
+		return "", err
+	}
+
+	for _, variant := range variants {
+		if err := s.images.Put(page, baseName+variant.Suffix, variant.Data); err != nil {
+			return "", err
+		}
+	}
+
+	return hash, nil
+}
+
+// linkOrCopy hard links src to dst, copying the file when linking is not possible
+func linkOrCopy(src string, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(dst, data, 0644)
+}
+
+// parseImageAlt splits the raw Notion caption of an image into its caption and alt text.
+// The alt text is expected after an "|alt:" marker, e.g. "A sunset|alt:Orange sky over the sea".
+// When the marker is missing, the caption is used as alt text, then the filename.
+// hasAlt is false when the image had neither an alt text nor a caption.
+func parseImageAlt(rawAlt string, filename string) (caption string, alt string, hasAlt bool) {
+	chunks := strings.SplitN(rawAlt, "|alt:", 2)
+	caption = strings.TrimSpace(chunks[0])
+	if len(chunks) == 2 {
+		alt = strings.TrimSpace(chunks[1])
+	}
+
+	if alt == "" {
+		alt = caption
+	}
+
+	if alt == "" {
+		return caption, filename, false
+	}
+
+	return caption, alt, true
+}
+
+// escapeShortcodeAttr makes a value safe to use inside a double-quoted shortcode attribute
+func escapeShortcodeAttr(value string) string {
+	value = strings.ReplaceAll(value, "\n", " ")
+	value = strings.ReplaceAll(value, "\"", "&quot;")
+	return value
+}
+
+// imageSrcset lists the renditions of an image ending with ext as a srcset attribute value.
+// It is empty when the image has no variant in that format.
+func (s *Syncer) imageSrcset(page PageLocation, hash string, image *ImageState, ext string) string {
+	var candidates []string
+	hasVariant := false
+	baseName := imageBaseName(hash)
+
+	if ext == image.Ext && image.Width > 0 {
+		candidates = append(candidates, fmt.Sprintf("%s %dw", s.images.URL(page, baseName+image.Ext), image.Width))
+	}
+
+	for _, variant := range image.Variants {
+		if strings.HasSuffix(variant.Suffix, ext) {
+			candidates = append(candidates, fmt.Sprintf("%s %dw", s.images.URL(page, baseName+variant.Suffix), variant.Width))
+			hasVariant = true
+		}
+	}
+
+	if !hasVariant {
+		return ""
+	}
+
+	return strings.Join(candidates, ", ")
+}
+
+// ImageInfo describes an image included in a page, as exposed to the image template and front matter
+type ImageInfo struct {
+	Src         string `yaml:"src"`
+	Caption     string `yaml:"caption,omitempty"`
+	Alt         string `yaml:"alt,omitempty"`
+	Width       int    `yaml:"width,omitempty"`
+	Height      int    `yaml:"height,omitempty"`
+	Placeholder string `yaml:"placeholder,omitempty"`
+	Srcset      string `yaml:"srcset,omitempty"`
+	WebpSrcset  string `yaml:"webpSrcset,omitempty"`
+}
+
+// defaultImageTemplate renders images with the figure shortcode. Templates use [[ ]] as
+// delimiters so that Hugo's own {{ }} can be written as is.
+const defaultImageTemplate = `{{< figure src="[[ .Src ]]" caption="[[ attr .Caption ]]" alt="[[ attr .Alt ]]"` +
+	`[[ with .Width ]] width="[[ . ]]"[[ end ]][[ with .Height ]] height="[[ . ]]"[[ end ]]` +
+	`[[ with .Srcset ]] srcset="[[ . ]]"[[ end ]][[ with .WebpSrcset ]] webpSrcset="[[ . ]]"[[ end ]]` +
+	`[[ with .Placeholder ]] placeholder="[[ attr . ]]"[[ end ]]` +
+	` position="center" captionStyle="font-style: italic;" >}}`
+
+// newShortcodeTemplate parses a template rendering content for Hugo, with [[ ]] as delimiters
+func newShortcodeTemplate(name string, text string) (*template.Template, error) {
+	return template.New(name).
+		Delims("[[", "]]").
+		Funcs(template.FuncMap{"attr": escapeShortcodeAttr}).
+		Parse(text)
+}
+
+// imageTemplate parses the image template from the configuration, falling back on the default one
+func imageTemplate() (*template.Template, error) {
+	if text := viper.GetString("image_template"); text != "" {
+		tmpl, err := newShortcodeTemplate("image", text)
+		if err == nil {
+			return tmpl, nil
+		}
+
+		defaultTmpl, _ := newShortcodeTemplate("image", defaultImageTemplate)
+		return defaultTmpl, err
+	}
+
+	return newShortcodeTemplate("image", defaultImageTemplate)
+}
+
+// processImages processes all images in the markdown content, and returns the images it included
+func (s *Syncer) processImages(markdown string, page PageLocation, pageTitle string) (string, []ImageInfo) {
+	var images []ImageInfo
+
+	tmpl, err := imageTemplate()
+	if err != nil {
+		s.addResult(SyncResult{
+			PageTitle:   pageTitle,
+			Status:      "Warning",
+			Path:        page.Dir,
+			LastUpdated: time.Now(),
+			Message:     fmt.Sprintf("invalid image template, using the default one: %v", err),
+		})
+	}
+
+	markdown = imageRegex.ReplaceAllStringFunc(markdown, func(match string) string {
+		submatches := imageRegex.FindStringSubmatch(match)
+		if len(submatches) != 3 {
+			return match
+		}
+
+		rawAlt := submatches[1]
+		imageURL := submatches[2]
+
+		originalName := generateImageFilename(imageURL)
+		caption, alt, hasAlt := parseImageAlt(rawAlt, originalName)
+
+		// Download the image, or reuse the local copy
+		hash, err := s.resolveImage(imageURL, page, originalName)
+		if err != nil {
+			// If download fails, return original markdown
+			return match
+		}
+
+		image := s.state.Images[hash]
+		filename := imageBaseName(hash) + image.Ext
+		imageURI := s.images.URL(page, filename)
+
+		if !hasAlt {
+			s.addResult(SyncResult{
+				PageTitle:   pageTitle,
+				Status:      "Warning",
+				Path:        imageURI,
+				LastUpdated: time.Now(),
+				Message:     "image has no alt text",
+			})
+		}
+
+		if image.WebPDropped {
+			s.addResult(SyncResult{
+				PageTitle:   pageTitle,
+				Status:      "Warning",
+				Path:        imageURI,
+				LastUpdated: time.Now(),
+				Message:     "WebP variants left out: lossless WebP is larger than the original image",
+			})
+		}
+
+		info := ImageInfo{
+			Src:         imageURI,
+			Caption:     caption,
+			Alt:         alt,
+			Width:       image.Width,
+			Height:      image.Height,
+			Placeholder: image.Placeholder,
+			Srcset:      s.imageSrcset(page, hash, image, image.Ext),
+			WebpSrcset:  s.imageSrcset(page, hash, image, ".webp"),
+		}
+		images = append(images, info)
+
+		var shortcode strings.Builder
+		if err := tmpl.Execute(&shortcode, info); err != nil {
+			s.addResult(SyncResult{
+				PageTitle:   pageTitle,
+				Status:      "Warning",
+				Path:        imageURI,
+				LastUpdated: time.Now(),
+				Message:     fmt.Sprintf("failed to render image template: %v", err),
+			})
+			return match
+		}
+
+		return "\n\n" + shortcode.String() + "\n\n"
+	})
+
+	return markdown, images
+}
@@ -0,0 +1,132 @@
+package sync
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseImageAlt(t *testing.T) {
+	tests := []struct {
+		name        string
+		rawAlt      string
+		wantCaption string
+		wantAlt     string
+		wantHasAlt  bool
+	}{
+		{name: "caption and alt", rawAlt: "A cat|alt:A cat sleeping on a sofa", wantCaption: "A cat", wantAlt: "A cat sleeping on a sofa", wantHasAlt: true},
+		{name: "no alt marker", rawAlt: "A cat", wantCaption: "A cat", wantAlt: "A cat", wantHasAlt: true},
+		{name: "empty alt", rawAlt: "A cat|alt:  ", wantCaption: "A cat", wantAlt: "A cat", wantHasAlt: true},
+		{name: "empty caption", rawAlt: "|alt:A cat", wantCaption: "", wantAlt: "A cat", wantHasAlt: true},
+		{name: "nothing", rawAlt: "", wantCaption: "", wantAlt: "cat.png", wantHasAlt: false},
+		{name: "quotes in the caption", rawAlt: `The "cat" |alt:`, wantCaption: `The "cat"`, wantAlt: `The "cat"`, wantHasAlt: true},
+		{name: "marker in the alt", rawAlt: "A|alt:B|alt:C", wantCaption: "A", wantAlt: "B|alt:C", wantHasAlt: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caption, alt, hasAlt := parseImageAlt(tt.rawAlt, "cat.png")
+			if caption != tt.wantCaption || alt != tt.wantAlt || hasAlt != tt.wantHasAlt {
+				t.Errorf("parseImageAlt(%q) = %q, %q, %v, want %q, %q, %v", tt.rawAlt, caption, alt, hasAlt, tt.wantCaption, tt.wantAlt, tt.wantHasAlt)
+			}
+		})
+	}
+}
+
+func TestEscapeShortcodeAttr(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "plain", value: "A cat", want: "A cat"},
+		{name: "empty", value: "", want: ""},
+		{name: "quotes", value: `The "cat"`, want: "The &quot;cat&quot;"},
+		{name: "new lines", value: "A cat\non a sofa", want: "A cat on a sofa"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escapeShortcodeAttr(tt.value); got != tt.want {
+				t.Errorf("escapeShortcodeAttr(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultImageTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		info ImageInfo
+		want string
+	}{
+		{
+			name: "minimal",
+			info: ImageInfo{Src: "/posts/a/images/cat.png", Alt: "cat.png"},
+			want: `{{< figure src="/posts/a/images/cat.png" caption="" alt="cat.png" position="center" captionStyle="font-style: italic;" >}}`,
+		},
+		{
+			name: "dimensions and placeholder",
+			info: ImageInfo{Src: "/cat.png", Caption: `A "cat"`, Alt: "A cat", Width: 40, Height: 20, Placeholder: "LEHV6nWB2yk8"},
+			want: `{{< figure src="/cat.png" caption="A &quot;cat&quot;" alt="A cat" width="40" height="20" placeholder="LEHV6nWB2yk8" position="center" captionStyle="font-style: italic;" >}}`,
+		},
+		{
+			name: "srcsets",
+			info: ImageInfo{Src: "/cat.png", Alt: "A cat", Srcset: "/cat-320w.png 320w", WebpSrcset: "/cat-320w.webp 320w"},
+			want: `{{< figure src="/cat.png" caption="" alt="A cat" srcset="/cat-320w.png 320w" webpSrcset="/cat-320w.webp 320w" position="center" captionStyle="font-style: italic;" >}}`,
+		},
+	}
+
+	tmpl, err := imageTemplate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got strings.Builder
+			if err := tmpl.Execute(&got, tt.info); err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("image template = %s, want %s", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestImageSourceKey(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		same bool
+	}{
+		{
+			name: "Notion-hosted URLs signed differently",
+			a:    "https://prod-files-secure.s3.us-west-2.amazonaws.com/ws/0123/image.png?X-Amz-Signature=aaa&X-Amz-Date=1",
+			b:    "https://prod-files-secure.s3.us-west-2.amazonaws.com/ws/0123/image.png?X-Amz-Signature=bbb&X-Amz-Date=2",
+			same: true,
+		},
+		{
+			name: "external URLs with a different query",
+			a:    "https://quickchart.io/chart?c=A",
+			b:    "https://quickchart.io/chart?c=B",
+			same: false,
+		},
+		{
+			name: "external URLs with the same query",
+			a:    "https://example.com/image.png?w=200",
+			b:    "https://example.com/image.png?w=200",
+			same: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyA, keyB := imageSourceKey(tt.a), imageSourceKey(tt.b)
+			if (keyA == keyB) != tt.same {
+				t.Errorf("imageSourceKey(%q) = %q, imageSourceKey(%q) = %q, same = %v, want %v", tt.a, keyA, tt.b, keyB, keyA == keyB, tt.same)
+			}
+		})
+	}
+}
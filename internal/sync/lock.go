@@ -0,0 +1,304 @@
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+const (
+	lockFileName  = "sync.lock"
+	queueFileName = "sync.queue"
+)
+
+// The holder of a lock rewrites it periodically, so a lock left by a crashed run on another host,
+// whose PID cannot be checked, goes stale after a few missed refreshes
+const (
+	lockRefreshInterval = 30 * time.Second
+	lockStaleAfter      = 2 * time.Minute
+	lockPollInterval    = time.Second
+	lockTakeoverDelay   = time.Second
+)
+
+// LockInfo tells which run holds a lock
+type LockInfo struct {
+	PID      int       `json:"pid"`
+	Hostname string    `json:"hostname"`
+	Started  time.Time `json:"started"`
+	Updated  time.Time `json:"updated"`
+}
+
+func (info LockInfo) String() string {
+	return fmt.Sprintf("pid %d on %s, started at %s", info.PID, info.Hostname, info.Started.Format("15:04:05"))
+}
+
+// Lock is an advisory lock, held in the state directory by the run syncing its content directory.
+// Since it lives with the state, runs using different state directories for the same content
+// directory are not kept apart.
+type Lock struct {
+	path string
+	info LockInfo
+	stop chan struct{}
+	done chan struct{}
+}
+
+// LockedError is returned when another run holds the lock
+type LockedError struct {
+	Path   string
+	Holder LockInfo
+	Queued bool
+}
+
+func (e *LockedError) Error() string {
+	if e.Queued {
+		return fmt.Sprintf("another sync (%s) is already queued behind the running one", e.Holder)
+	}
+
+	return fmt.Sprintf("another sync (%s) is running, remove %s if it is stale", e.Holder, e.Path)
+}
+
+// AcquireLock takes the lock of stateDir. When another run holds it, the lock_mode decides what
+// happens: "exit" fails right away, "wait" retries until timeout, and "queue" waits too, unless
+// another run is already waiting, since a single queued run syncs the changes of both.
+// waiting is called once when the run starts waiting. A zero timeout waits forever.
+func AcquireLock(stateDir string, mode string, timeout time.Duration, waiting func(holder LockInfo)) (*Lock, error) {
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return nil, err
+	}
+
+	lock := &Lock{
+		path: filepath.Join(stateDir, lockFileName),
+		info: newLockInfo(),
+	}
+
+	holder, err := createLockFile(lock.path, lock.info)
+	if err == nil {
+		lock.start()
+		return lock, nil
+	}
+	if !errors.Is(err, fs.ErrExist) || (mode != "wait" && mode != "queue") {
+		return nil, lockError(lock.path, holder, err)
+	}
+
+	if mode == "queue" {
+		queuePath := filepath.Join(stateDir, queueFileName)
+		if queued, err := createLockFile(queuePath, lock.info); err != nil {
+			if errors.Is(err, fs.ErrExist) {
+				return nil, &LockedError{Path: queuePath, Holder: queued, Queued: true}
+			}
+			return nil, err
+		}
+		defer removeLockFile(queuePath, lock.info)
+	}
+
+	if waiting != nil {
+		waiting(holder)
+	}
+
+	started := time.Now()
+	lastRefresh := started
+	for {
+		time.Sleep(lockPollInterval)
+
+		holder, err = createLockFile(lock.path, lock.info)
+		if err == nil {
+			lock.start()
+			return lock, nil
+		}
+		if !errors.Is(err, fs.ErrExist) || (timeout > 0 && time.Since(started) > timeout) {
+			return nil, lockError(lock.path, holder, err)
+		}
+
+		// The queue file must not go stale while waiting
+		if mode == "queue" && time.Since(lastRefresh) > lockRefreshInterval {
+			lock.info.Updated = time.Now()
+			writeLockInfo(filepath.Join(stateDir, queueFileName), lock.info)
+			lastRefresh = time.Now()
+		}
+	}
+}
+
+// Release drops the lock, if it is still held by this run
+func (l *Lock) Release() {
+	close(l.stop)
+	<-l.done
+	removeLockFile(l.path, l.info)
+}
+
+// start refreshes the lock in the background until it is released, or taken over by another run
+func (l *Lock) start() {
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+
+	go func() {
+		defer close(l.done)
+
+		ticker := time.NewTicker(lockRefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-ticker.C:
+				// Another run took the lock over, it is no longer this run's to refresh
+				if holder, ok := readLockInfo(l.path); !ok || !holder.sameRun(l.info) {
+					return
+				}
+
+				l.info.Updated = time.Now()
+				writeLockInfo(l.path, l.info)
+			}
+		}
+	}()
+}
+
+func newLockInfo() LockInfo {
+	hostname, _ := os.Hostname()
+	now := time.Now()
+
+	return LockInfo{
+		PID:      os.Getpid(),
+		Hostname: hostname,
+		Started:  now,
+		Updated:  now,
+	}
+}
+
+func lockError(path string, holder LockInfo, err error) error {
+	if errors.Is(err, fs.ErrExist) {
+		return &LockedError{Path: path, Holder: holder}
+	}
+
+	return err
+}
+
+// createLockFile creates a lock file, unless a run which is still alive holds it. Stale lock files
+// are replaced. It returns the holder of the lock when it exists.
+func createLockFile(path string, info LockInfo) (LockInfo, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err == nil {
+		file.Close()
+		return info, writeLockInfo(path, info)
+	}
+	if !errors.Is(err, fs.ErrExist) {
+		return LockInfo{}, err
+	}
+
+	holder, ok := readLockInfo(path)
+	if !ok || !holder.isStale() {
+		return holder, err
+	}
+
+	return takeOverLockFile(path, info)
+}
+
+// takeOverLockFile replaces a stale lock file. Several runs may find it stale at once: each one
+// renames its own lock file over it, and the run whose lock file is still there after a moment
+// holds the lock.
+func takeOverLockFile(path string, info LockInfo) (LockInfo, error) {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return LockInfo{}, err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return LockInfo{}, err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return LockInfo{}, err
+	}
+
+	time.Sleep(lockTakeoverDelay)
+
+	holder, ok := readLockInfo(path)
+	if !ok || !holder.sameRun(info) {
+		return holder, &fs.PathError{Op: "lock", Path: path, Err: fs.ErrExist}
+	}
+
+	return info, nil
+}
+
+// removeLockFile deletes a lock file if it was written by the given run
+func removeLockFile(path string, info LockInfo) {
+	holder, ok := readLockInfo(path)
+	if ok && holder.sameRun(info) {
+		os.Remove(path)
+	}
+}
+
+func readLockInfo(path string) (LockInfo, bool) {
+	var info LockInfo
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return info, false
+	}
+
+	// A lock being written is empty for a moment, it is only stale if it stays so
+	if err := json.Unmarshal(data, &info); err != nil {
+		if stat, err := os.Stat(path); err == nil && time.Since(stat.ModTime()) > lockStaleAfter {
+			return LockInfo{Updated: stat.ModTime()}, true
+		}
+		return info, false
+	}
+
+	return info, true
+}
+
+func writeLockInfo(path string, info LockInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// sameRun reports whether two lock infos were written by the same run
+func (info LockInfo) sameRun(other LockInfo) bool {
+	return info.PID == other.PID && info.Hostname == other.Hostname && info.Started.Equal(other.Started)
+}
+
+// isStale reports whether the run holding a lock is gone. On this host, it is decided by the PID
+// alone, since a suspended machine or a long pause would miss refreshes. The process of a run on
+// another host cannot be checked, so its lock goes stale once it stops being refreshed.
+func (info LockInfo) isStale() bool {
+	hostname, _ := os.Hostname()
+	if info.Hostname == hostname {
+		return !processAlive(info.PID)
+	}
+
+	return time.Since(info.Updated) > lockStaleAfter
+}
+
+// processAlive reports whether a process is running on this host
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	// Processes cannot be signaled on Windows, where FindProcess already fails for missing ones
+	if runtime.GOOS == "windows" {
+		return true
+	}
+
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, os.ErrPermission)
+}
@@ -0,0 +1,174 @@
+package sync
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// exitedPID returns the PID of a process which already exited
+func exitedPID(t *testing.T) int {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	return cmd.Process.Pid
+}
+
+func TestLockInfoIsStale(t *testing.T) {
+	hostname, _ := os.Hostname()
+	longAgo := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name string
+		info LockInfo
+		want bool
+	}{
+		{
+			name: "running on this host",
+			info: LockInfo{PID: os.Getpid(), Hostname: hostname, Updated: time.Now()},
+			want: false,
+		},
+		{
+			name: "running on this host without refreshing the lock",
+			info: LockInfo{PID: os.Getpid(), Hostname: hostname, Updated: longAgo},
+			want: false,
+		},
+		{
+			name: "exited on this host",
+			info: LockInfo{PID: exitedPID(t), Hostname: hostname, Updated: time.Now()},
+			want: true,
+		},
+		{
+			name: "refreshed on another host",
+			info: LockInfo{PID: os.Getpid(), Hostname: hostname + "-other", Updated: time.Now()},
+			want: false,
+		},
+		{
+			name: "no longer refreshed on another host",
+			info: LockInfo{PID: os.Getpid(), Hostname: hostname + "-other", Updated: longAgo},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.info.isStale(); got != tt.want {
+				t.Errorf("isStale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAcquireLock(t *testing.T) {
+	hostname, _ := os.Hostname()
+
+	tests := []struct {
+		name    string
+		holder  *LockInfo
+		wantErr bool
+	}{
+		{
+			name: "no lock",
+		},
+		{
+			name:    "held by a running sync",
+			holder:  &LockInfo{PID: os.Getpid(), Hostname: hostname, Started: time.Now().Add(-time.Minute), Updated: time.Now()},
+			wantErr: true,
+		},
+		{
+			name:   "left by a crashed sync",
+			holder: &LockInfo{PID: exitedPID(t), Hostname: hostname, Started: time.Now(), Updated: time.Now()},
+		},
+		{
+			name:   "left on another host",
+			holder: &LockInfo{PID: 1, Hostname: hostname + "-other", Updated: time.Now().Add(-time.Hour)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stateDir := t.TempDir()
+			path := filepath.Join(stateDir, lockFileName)
+			if tt.holder != nil {
+				if err := writeLockInfo(path, *tt.holder); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			lock, err := AcquireLock(stateDir, "exit", 0, nil)
+			if tt.wantErr {
+				var lockedErr *LockedError
+				if !errors.As(err, &lockedErr) {
+					t.Fatalf("AcquireLock() error = %v, want a LockedError", err)
+				}
+				if !lockedErr.Holder.sameRun(*tt.holder) {
+					t.Errorf("holder = %v, want %v", lockedErr.Holder, *tt.holder)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AcquireLock() error = %v", err)
+			}
+
+			if holder, ok := readLockInfo(path); !ok || !holder.sameRun(lock.info) {
+				t.Errorf("lock file holds %v, want this run", holder)
+			}
+
+			lock.Release()
+			if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("lock file still there after Release(): %v", err)
+			}
+		})
+	}
+}
+
+func TestAcquireLockWait(t *testing.T) {
+	stateDir := t.TempDir()
+
+	first, err := AcquireLock(stateDir, "exit", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		first.Release()
+	}()
+
+	waited := false
+	second, err := AcquireLock(stateDir, "wait", time.Minute, func(LockInfo) { waited = true })
+	if err != nil {
+		t.Fatalf("AcquireLock() error = %v", err)
+	}
+	defer second.Release()
+
+	if !waited {
+		t.Error("waiting callback not called")
+	}
+}
+
+func TestAcquireLockQueue(t *testing.T) {
+	stateDir := t.TempDir()
+
+	running, err := AcquireLock(stateDir, "exit", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer running.Release()
+
+	// A run is already queued behind the running one
+	if err := writeLockInfo(filepath.Join(stateDir, queueFileName), newLockInfo()); err != nil {
+		t.Fatal(err)
+	}
+
+	var lockedErr *LockedError
+	if _, err := AcquireLock(stateDir, "queue", time.Minute, nil); !errors.As(err, &lockedErr) || !lockedErr.Queued {
+		t.Errorf("AcquireLock() error = %v, want a queued LockedError", err)
+	}
+}
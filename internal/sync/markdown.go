@@ -0,0 +1,188 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	gosync "sync"
+	"time"
+
+	"github.com/jomei/notionapi"
+	"github.com/ma111e/notion2markdown"
+)
+
+// Converted pages still link to the Notion-hosted files, whose signed URLs expire after an hour
+const markdownCacheTTL = 30 * time.Minute
+
+// MarkdownCache keeps the Markdown converted from Notion pages, so that a page previewed in the
+// selector is not fetched again when it is synced
+type MarkdownCache struct {
+	mu      gosync.Mutex
+	entries map[string]cachedMarkdown
+}
+
+type cachedMarkdown struct {
+	markdown       string
+	lastEditedTime time.Time
+	convertedAt    time.Time
+}
+
+func NewMarkdownCache() *MarkdownCache {
+	return &MarkdownCache{
+		entries: make(map[string]cachedMarkdown),
+	}
+}
+
+// get returns the Markdown of a page if it was converted recently and not edited since
+func (c *MarkdownCache) get(pageID string, lastEditedTime time.Time) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[NormalizePageID(pageID)]
+	if !ok || !entry.lastEditedTime.Equal(lastEditedTime) || time.Since(entry.convertedAt) > markdownCacheTTL {
+		return "", false
+	}
+
+	return entry.markdown, true
+}
+
+// put keeps the Markdown of a page. Notion rounds last_edited_time down to the minute, so a page
+// edited during the current minute is not kept: a later edit in the same minute would go unnoticed.
+func (c *MarkdownCache) put(pageID string, lastEditedTime time.Time, markdown string) {
+	if c == nil || time.Now().Before(lastEditedTime.Truncate(time.Minute).Add(time.Minute)) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[NormalizePageID(pageID)] = cachedMarkdown{
+		markdown:       markdown,
+		lastEditedTime: lastEditedTime,
+		convertedAt:    time.Now(),
+	}
+}
+
+// PageMarkdown converts a page to Markdown as the sync does, before its images and attachments are
+// processed. The result is kept in the cache for the sync.
+func PageMarkdown(client *notionapi.Client, cache *MarkdownCache, pageID string, lastEditedTime time.Time) (string, error) {
+	s := &Syncer{client: client, markdownCache: cache}
+	return s.cachedPageToMarkdown(pageID, lastEditedTime)
+}
+
+// UseMarkdownCache makes the syncer reuse the pages converted for the preview of the selector
+func (s *Syncer) UseMarkdownCache(cache *MarkdownCache) {
+	s.markdownCache = cache
+}
+
+// cachedPageToMarkdown converts a page to Markdown, unless it was converted since its last edition
+func (s *Syncer) cachedPageToMarkdown(pageID string, lastEditedTime time.Time) (string, error) {
+	if markdown, ok := s.markdownCache.get(pageID, lastEditedTime); ok {
+		return markdown, nil
+	}
+
+	markdown, err := s.pageToMarkdown(pageID)
+	if err != nil {
+		return "", err
+	}
+
+	s.markdownCache.put(pageID, lastEditedTime, markdown)
+	return markdown, nil
+}
+
+// fetchBlocks returns all the child blocks of a block, following pagination
+func (s *Syncer) fetchBlocks(blockID string) ([]notionapi.Block, error) {
+	var blocks []notionapi.Block
+	pagination := notionapi.Pagination{
+		PageSize: 100,
+	}
+
+	for {
+		resp, err := s.client.Block.GetChildren(context.Background(), notionapi.BlockID(blockID), &pagination)
+		if err != nil {
+			return nil, err
+		}
+
+		blocks = append(blocks, resp.Results...)
+		if !resp.HasMore || resp.NextCursor == "" {
+			return blocks, nil
+		}
+		pagination.StartCursor = notionapi.Cursor(resp.NextCursor)
+	}
+}
+
+// pageToMarkdown converts the content of a page to Markdown.
+// notion2markdown renders the text blocks while images and attachments are handled here: they
+// come out as Markdown images and links, to be picked up by processImages and processAssets.
+func (s *Syncer) pageToMarkdown(pageID string) (string, error) {
+	blocks, err := s.fetchBlocks(pageID)
+	if err != nil {
+		return "", err
+	}
+
+	var markdown strings.Builder
+	var textBlocks []notionapi.Block
+
+	for _, block := range blocks {
+		var rendered string
+
+		switch b := block.(type) {
+		case *notionapi.ImageBlock:
+			rendered = mediaToMarkdown("image", b.Image.Caption, fileURL(b.Image.File, b.Image.External))
+		case *notionapi.FileBlock:
+			rendered = mediaToMarkdown("file", b.File.Caption, fileURL(b.File.File, b.File.External))
+		case *notionapi.PdfBlock:
+			rendered = mediaToMarkdown("pdf", b.Pdf.Caption, fileURL(b.Pdf.File, b.Pdf.External))
+		case *notionapi.VideoBlock:
+			rendered = mediaToMarkdown("video", b.Video.Caption, fileURL(b.Video.File, b.Video.External))
+		case *notionapi.AudioBlock:
+			rendered = mediaToMarkdown("audio", b.Audio.Caption, fileURL(b.Audio.File, b.Audio.External))
+		default:
+			textBlocks = append(textBlocks, block)
+			continue
+		}
+
+		// Text blocks are converted in runs so that lists keep their spacing
+		markdown.WriteString(notion2markdown.BlocksToMarkdown(textBlocks))
+		textBlocks = nil
+		markdown.WriteString(rendered)
+	}
+	markdown.WriteString(notion2markdown.BlocksToMarkdown(textBlocks))
+
+	return markdown.String(), nil
+}
+
+// fileURL returns the URL of a Notion-hosted or external file
+func fileURL(file *notionapi.FileObject, external *notionapi.FileObject) string {
+	if file != nil {
+		return file.URL
+	}
+	if external != nil {
+		return external.URL
+	}
+	return ""
+}
+
+// mediaToMarkdown renders a media block as a Markdown image, or as a link titled after its kind
+func mediaToMarkdown(kind string, caption []notionapi.RichText, mediaURL string) string {
+	if mediaURL == "" {
+		return ""
+	}
+
+	var text []string
+	for _, richText := range caption {
+		text = append(text, richText.PlainText)
+	}
+	// Brackets and line breaks would end the Markdown label early
+	label := strings.NewReplacer("\n", " ", "[", "(", "]", ")").Replace(strings.Join(text, ""))
+
+	if kind == "image" {
+		return fmt.Sprintf("![%s](%s)", label, mediaURL)
+	}
+
+	return fmt.Sprintf("[%s](%s \"%s\")\n", label, mediaURL, kind)
+}
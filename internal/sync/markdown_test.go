@@ -0,0 +1,84 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jomei/notionapi"
+)
+
+func TestMediaToMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		kind     string
+		caption  []notionapi.RichText
+		mediaURL string
+		want     string
+	}{
+		{
+			name:     "image with a caption",
+			kind:     "image",
+			caption:  []notionapi.RichText{{PlainText: "A cat"}, {PlainText: "|alt:A cat on a sofa"}},
+			mediaURL: "https://file.notion.so/image.png",
+			want:     "![A cat|alt:A cat on a sofa](https://file.notion.so/image.png)",
+		},
+		{
+			name:     "image without a caption",
+			kind:     "image",
+			mediaURL: "https://file.notion.so/image.png",
+			want:     "![](https://file.notion.so/image.png)",
+		},
+		{
+			name:     "brackets and line breaks",
+			kind:     "image",
+			caption:  []notionapi.RichText{{PlainText: "A [cat]\non a sofa"}},
+			mediaURL: "https://file.notion.so/image.png",
+			want:     "![A (cat) on a sofa](https://file.notion.so/image.png)",
+		},
+		{
+			name:     "attachment",
+			kind:     "pdf",
+			caption:  []notionapi.RichText{{PlainText: "Report"}},
+			mediaURL: "https://file.notion.so/report.pdf",
+			want:     "[Report](https://file.notion.so/report.pdf \"pdf\")\n",
+		},
+		{
+			name: "no file",
+			kind: "image",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mediaToMarkdown(tt.kind, tt.caption, tt.mediaURL); got != tt.want {
+				t.Errorf("mediaToMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownCache(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name           string
+		lastEditedTime time.Time
+		want           bool
+	}{
+		{name: "edited minutes ago", lastEditedTime: now.Add(-5 * time.Minute).Truncate(time.Minute), want: true},
+		{name: "edited in the current minute", lastEditedTime: now.Truncate(time.Minute), want: false},
+		{name: "edited in the future", lastEditedTime: now.Add(time.Hour), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewMarkdownCache()
+			cache.put("page", tt.lastEditedTime, "content")
+
+			if _, ok := cache.get("page", tt.lastEditedTime); ok != tt.want {
+				t.Errorf("get() found the page = %t, want %t", ok, tt.want)
+			}
+		})
+	}
+}
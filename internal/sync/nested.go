@@ -0,0 +1,140 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/jomei/notionapi"
+)
+
+// Maximum number of parents walked up to find the root page
+const maxNestingDepth = 32
+
+// syncNestedPages syncs the selected pages and databases that are neither children of the root page
+// nor rows of its databases, such as sub-pages picked in the selection tree. Each of them is written
+// under the post of its parent page.
+func (s *Syncer) syncNestedPages(rootPageID string, syncTime time.Time) {
+	for _, selected := range s.selectedPages {
+		id := NormalizePageID(selected)
+		if s.foundPages[id] {
+			continue
+		}
+
+		// Nested pages are never deleted, so the synced directories are not needed
+		var syncedHugoPageDirs []string
+
+		if notionPage, err := s.fetchPage(id); err == nil {
+			s.foundPages[id] = true
+			hugoPageDir, err := s.nestedPageDir(notionPage.Parent, rootPageID)
+			if err != nil {
+				s.addNotFoundResult(selected, err)
+				continue
+			}
+
+			entry := pageEntry{
+				ID:             string(notionPage.ID),
+				Title:          PageTitle(notionPage),
+				ParentID:       parentID(notionPage.Parent),
+				CreatedTime:    notionPage.CreatedTime,
+				LastEditedTime: notionPage.LastEditedTime,
+				Page:           notionPage,
+			}
+			info := PageInfo{ID: entry.ID, Title: entry.Title, ParentID: string(notionPage.Parent.DatabaseID), Properties: notionPage.Properties}
+			if s.filter.Allows(info) {
+				s.addPagesToSync(1)
+				s.syncChildPage(entry, hugoPageDir, syncTime, &syncedHugoPageDirs)
+			}
+			continue
+		}
+
+		database, err := s.client.Database.Get(context.Background(), notionapi.DatabaseID(id))
+		if err != nil {
+			continue
+		}
+
+		s.foundPages[id] = true
+		hugoPageDir, err := s.nestedPageDir(database.Parent, rootPageID)
+		if err != nil {
+			s.addNotFoundResult(selected, err)
+			continue
+		}
+
+		entries, _ := s.databaseEntries(id, richTextValue(database.Title), hugoPageDir, &syncedHugoPageDirs)
+		s.addPagesToSync(len(entries))
+		for _, entry := range entries {
+			s.syncChildPage(entry, hugoPageDir, syncTime, &syncedHugoPageDirs)
+		}
+	}
+}
+
+// parentID returns the ID of the page, database or block holding a page
+func parentID(parent notionapi.Parent) string {
+	switch parent.Type {
+	case notionapi.ParentTypePageID:
+		return string(parent.PageID)
+	case notionapi.ParentTypeDatabaseID:
+		return string(parent.DatabaseID)
+	case notionapi.ParentTypeBlockID:
+		return string(parent.BlockID)
+	}
+
+	return ""
+}
+
+// addNotFoundResult reports a selected page that could not be placed under the root page
+func (s *Syncer) addNotFoundResult(selected string, err error) {
+	s.addResult(SyncResult{
+		PageTitle:   selected,
+		Status:      "Warning",
+		Path:        s.contentDir,
+		LastUpdated: time.Now(),
+		Message:     fmt.Sprintf("selected page not found under the root page: %v", err),
+	})
+}
+
+// nestedPageDir returns the directory where the children of a parent are written, walking up its
+// parents until the root page. Databases and blocks such as columns add no directory.
+func (s *Syncer) nestedPageDir(parent notionapi.Parent, rootPageID string) (string, error) {
+	var slugs []string
+
+	for range maxNestingDepth {
+		switch parent.Type {
+		case notionapi.ParentTypePageID:
+			if NormalizePageID(string(parent.PageID)) == NormalizePageID(rootPageID) {
+				return filepath.Join(append([]string{s.contentDir}, slugs...)...), nil
+			}
+
+			parentPage, err := s.fetchPage(string(parent.PageID))
+			if err != nil {
+				return "", err
+			}
+			slugs = slices.Insert(slugs, 0, pageSlug(PageTitle(parentPage)))
+			parent = parentPage.Parent
+
+		case notionapi.ParentTypeDatabaseID:
+			database, err := s.client.Database.Get(context.Background(), parent.DatabaseID)
+			if err != nil {
+				return "", err
+			}
+			parent = database.Parent
+
+		case notionapi.ParentTypeBlockID:
+			block, err := s.client.Block.Get(context.Background(), parent.BlockID)
+			if err != nil {
+				return "", err
+			}
+			if block.GetParent() == nil {
+				return "", fmt.Errorf("block %s has no parent", parent.BlockID)
+			}
+			parent = *block.GetParent()
+
+		default:
+			return "", fmt.Errorf("not under the root page")
+		}
+	}
+
+	return "", fmt.Errorf("nested more than %d levels under the root page", maxNestingDepth)
+}
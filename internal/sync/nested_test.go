@@ -0,0 +1,100 @@
+package sync
+
+import (
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jomei/notionapi"
+)
+
+// fakeNotion answers the Notion API requests from canned JSON bodies, keyed by request path
+type fakeNotion map[string]string
+
+func (f fakeNotion) RoundTrip(r *http.Request) (*http.Response, error) {
+	body, ok := f[strings.TrimPrefix(r.URL.Path, "/v1")]
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusNotFound
+		body = `{"object":"error","status":404,"code":"object_not_found","message":"not found"}`
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    r,
+	}, nil
+}
+
+func newFakeNotionClient(responses fakeNotion) *notionapi.Client {
+	return notionapi.NewClient("token", notionapi.WithHTTPClient(&http.Client{Transport: responses}))
+}
+
+func TestNestedPageDir(t *testing.T) {
+	const (
+		rootID     = "00000000000000000000000000000000"
+		sectionID  = "11111111111111111111111111111111"
+		columnID   = "22222222222222222222222222222222"
+		databaseID = "33333333333333333333333333333333"
+	)
+
+	client := newFakeNotionClient(fakeNotion{
+		"/pages/" + sectionID: `{"object":"page","id":"` + sectionID + `",
+			"parent":{"type":"page_id","page_id":"` + rootID + `"},
+			"properties":{"title":{"id":"title","type":"title","title":[{"type":"text","text":{"content":"Section A"},"plain_text":"Section A"}]}}}`,
+		"/blocks/" + columnID: `{"object":"block","id":"` + columnID + `","type":"paragraph","paragraph":{"rich_text":[]},
+			"parent":{"type":"page_id","page_id":"` + sectionID + `"}}`,
+		"/databases/" + databaseID: `{"object":"database","id":"` + databaseID + `","title":[],
+			"parent":{"type":"block_id","block_id":"` + columnID + `"}}`,
+	})
+
+	tests := []struct {
+		name    string
+		parent  notionapi.Parent
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "child of the root page",
+			parent: notionapi.Parent{Type: notionapi.ParentTypePageID, PageID: "00000000-0000-0000-0000-000000000000"},
+			want:   "content",
+		},
+		{
+			name:   "sub-page",
+			parent: notionapi.Parent{Type: notionapi.ParentTypePageID, PageID: sectionID},
+			want:   filepath.Join("content", "section_a"),
+		},
+		{
+			name:   "row of a database in a column",
+			parent: notionapi.Parent{Type: notionapi.ParentTypeDatabaseID, DatabaseID: databaseID},
+			want:   filepath.Join("content", "section_a"),
+		},
+		{
+			name:    "outside of the root page",
+			parent:  notionapi.Parent{Type: notionapi.ParentTypeWorkspace, Workspace: true},
+			wantErr: true,
+		},
+		{
+			name:    "unknown parent",
+			parent:  notionapi.Parent{Type: notionapi.ParentTypePageID, PageID: "44444444444444444444444444444444"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Syncer{client: client, contentDir: "content"}
+
+			got, err := s.nestedPageDir(tt.parent, rootID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("nestedPageDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("nestedPageDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
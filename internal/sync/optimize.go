@@ -0,0 +1,420 @@
+package sync
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"slices"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/buckket/go-blurhash"
+	"github.com/spf13/viper"
+	"golang.org/x/image/draw"
+)
+
+// ImageOptions configures the optional processing applied to downloaded images
+type ImageOptions struct {
+	Enabled   bool
+	MaxWidth  int
+	MaxHeight int
+	Quality   int
+	WebP      bool
+	Widths    []int
+	// Placeholder is the kind of low-quality placeholder to compute: "blurhash", "lqip" or empty for none
+	Placeholder string
+}
+
+// Images with more pixels are not decoded, their bitmap could exhaust the memory
+const maxImagePixels = 50_000_000
+
+// processedImage is the result of optimizing an image
+type processedImage struct {
+	Data     []byte
+	Width    int
+	Height   int
+	Variants []processedVariant
+	// WebPDropped tells that the WebP variants were left out for being larger than the renditions
+	WebPDropped bool
+}
+
+// processedVariant is an additional rendition of an image, stored next to it
+type processedVariant struct {
+	ImageVariant
+	Data []byte
+}
+
+func imageOptionsFromConfig() ImageOptions {
+	quality := viper.GetInt("image_quality")
+	if quality <= 0 || quality > 100 {
+		quality = jpeg.DefaultQuality
+	}
+
+	return ImageOptions{
+		Enabled:   viper.GetBool("optimize_images"),
+		MaxWidth:  viper.GetInt("image_max_width"),
+		MaxHeight: viper.GetInt("image_max_height"),
+		Quality:   quality,
+		WebP:      viper.GetBool("image_webp"),
+		Widths:    viper.GetIntSlice("image_srcset_widths"),
+
+		Placeholder: viper.GetString("image_placeholder"),
+	}
+}
+
+// profile summarizes the options so images processed with different settings can be told apart
+func (o ImageOptions) profile() string {
+	profile := "placeholder=" + o.Placeholder
+	if o.Enabled {
+		profile += fmt.Sprintf(" max=%dx%d quality=%d webp=%t widths=%v", o.MaxWidth, o.MaxHeight, o.Quality, o.WebP, o.Widths)
+	}
+
+	return profile
+}
+
+// analyzeImage returns the display dimensions of an image and its placeholder of the requested kind.
+// Formats that cannot be decoded, such as SVG, yield zero values.
+func analyzeImage(data []byte, placeholder string) (width int, height int, lqip string) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, ""
+	}
+
+	width, height = config.Width, config.Height
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	// Browsers apply the EXIF orientation, which swaps the sides of rotated pictures
+	if orientation >= 5 {
+		width, height = height, width
+	}
+
+	if placeholder == "" {
+		return width, height, ""
+	}
+
+	img, _, err := decodeImage(data)
+	if err != nil {
+		return width, height, ""
+	}
+	img = applyOrientation(img, orientation)
+
+	switch placeholder {
+	case "blurhash":
+		// The hash only keeps a few components, a thumbnail is plenty and much faster to encode
+		hash, err := blurhash.Encode(4, 3, fitImage(img, 32, 32))
+		if err == nil {
+			lqip = hash
+		}
+	case "lqip":
+		thumbnailFormat := "jpeg"
+		if format == "png" {
+			thumbnailFormat = "png" // Keep transparency
+		}
+
+		thumbnail, err := encodeImage(fitImage(img, 16, 16), thumbnailFormat, 40)
+		if err == nil {
+			lqip = fmt.Sprintf("data:image/%s;base64,%s", thumbnailFormat, base64.StdEncoding.EncodeToString(thumbnail))
+		}
+	}
+
+	return width, height, lqip
+}
+
+// optimizeImage resizes and re-encodes JPEG and PNG images, and renders the srcset and WebP variants.
+// Re-encoding drops every metadata block, EXIF included. Since WebP is lossless, the WebP variants
+// are only kept when all of them are smaller than the renditions they stand in for.
+func optimizeImage(data []byte, opts ImageOptions) (*processedImage, error) {
+	img, format, err := decodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if format != "jpeg" && format != "png" {
+		return nil, fmt.Errorf("unsupported format for optimization: %s", format)
+	}
+
+	if format == "jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+
+	originalBounds := img.Bounds()
+	img = fitImage(img, opts.MaxWidth, opts.MaxHeight)
+	bounds := img.Bounds()
+	ext := "." + map[string]string{"jpeg": "jpg", "png": "png"}[format]
+
+	result := &processedImage{
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+	}
+
+	result.Data, err = encodeImage(img, format, opts.Quality)
+	if err != nil {
+		return nil, err
+	}
+
+	// PNG is lossless too, a PNG which was not resized is kept as is when already better compressed
+	if format == "png" && bounds == originalBounds && len(result.Data) > len(data) {
+		result.Data = data
+	}
+
+	// Size of the rendition each WebP variant stands in for
+	replacedSizes := make(map[int]int)
+	replacedSizes[result.Width] = len(result.Data)
+
+	if opts.WebP {
+		webpData, err := encodeImage(img, "webp", opts.Quality)
+		if err != nil {
+			return nil, err
+		}
+		result.Variants = append(result.Variants, processedVariant{
+			ImageVariant: ImageVariant{Suffix: ".webp", Width: result.Width, Format: "webp"},
+			Data:         webpData,
+		})
+	}
+
+	widths := slices.Clone(opts.Widths)
+	slices.Sort(widths)
+	for _, width := range slices.Compact(widths) {
+		// Upscaling would only produce larger files for the same picture
+		if width <= 0 || width >= result.Width {
+			continue
+		}
+
+		resized := fitImage(img, width, 0)
+		for _, variantFormat := range []string{format, "webp"} {
+			if variantFormat == "webp" && !opts.WebP {
+				continue
+			}
+
+			variantExt := ext
+			if variantFormat == "webp" {
+				variantExt = ".webp"
+			}
+
+			variantData, err := encodeImage(resized, variantFormat, opts.Quality)
+			if err != nil {
+				return nil, err
+			}
+			if variantFormat == format {
+				replacedSizes[width] = len(variantData)
+			}
+
+			result.Variants = append(result.Variants, processedVariant{
+				ImageVariant: ImageVariant{Suffix: fmt.Sprintf("-%dw%s", width, variantExt), Width: width, Format: variantFormat},
+				Data:         variantData,
+			})
+		}
+	}
+
+	// A WebP srcset missing some widths would serve blurry pictures, so the variants go all together
+	for _, variant := range result.Variants {
+		if variant.Format == "webp" && len(variant.Data) >= replacedSizes[variant.Width] {
+			result.Variants = slices.DeleteFunc(result.Variants, func(other processedVariant) bool {
+				return other.Format == "webp"
+			})
+			result.WebPDropped = true
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// decodeImage decodes an image, unless it has more than maxImagePixels pixels
+func decodeImage(data []byte) (image.Image, string, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if int64(config.Width)*int64(config.Height) > maxImagePixels {
+		return nil, "", fmt.Errorf("image too large to decode: %dx%d", config.Width, config.Height)
+	}
+
+	return image.Decode(bytes.NewReader(data))
+}
+
+// encodeImage encodes img in the given format. Quality only applies to JPEG;
+// PNG is always written with the best compression and WebP is lossless.
+func encodeImage(img image.Image, format string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	case "png":
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		err = encoder.Encode(&buf, img)
+	case "webp":
+		err = nativewebp.Encode(&buf, img, nil)
+	default:
+		err = fmt.Errorf("unsupported format: %s", format)
+	}
+
+	return buf.Bytes(), err
+}
+
+// fitImage scales img down to fit within maxWidth x maxHeight, keeping its aspect ratio.
+// A zero bound is not enforced.
+func fitImage(img image.Image, maxWidth int, maxHeight int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	scale := 1.0
+
+	if maxWidth > 0 && width > maxWidth {
+		scale = min(scale, float64(maxWidth)/float64(width))
+	}
+	if maxHeight > 0 && height > maxHeight {
+		scale = min(scale, float64(maxHeight)/float64(height))
+	}
+
+	if scale == 1.0 {
+		return img
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, max(1, int(float64(width)*scale)), max(1, int(float64(height)*scale))))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation tag of a JPEG, returning 1 (upright) when absent
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+2 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+
+		marker := data[i+1]
+		switch {
+		case marker == 0xFF:
+			i++ // Fill byte before a marker
+			continue
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			i += 2 // Standalone marker (TEM, RSTn), without a length
+			continue
+		case marker == 0xDA || marker == 0xD9:
+			return 1 // Image data starts, no more metadata
+		}
+
+		if i+4 > len(data) {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1 // Truncated or corrupted segment
+		}
+
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+
+		i += 2 + length
+	}
+
+	return 1
+}
+
+// tiffOrientation looks up the orientation tag (0x0112) in the first IFD of a TIFF header
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	// Compared before the conversion, an offset above 2^31 would wrap around on 32-bit platforms
+	rawOffset := order.Uint32(tiff[4:])
+	if uint64(rawOffset)+2 > uint64(len(tiff)) {
+		return 1
+	}
+	offset := int(rawOffset)
+
+	entries := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+
+	return 1
+}
+
+// applyOrientation rotates and flips img so it is displayed upright once the EXIF data is gone.
+// Pixels are copied between the NRGBA buffers directly, At and Set being far too slow for photos.
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	src, ok := img.(*image.NRGBA)
+	if !ok {
+		src = image.NewNRGBA(img.Bounds())
+		draw.Draw(src, src.Bounds(), img, img.Bounds().Min, draw.Src)
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < height; y++ {
+		row := src.Pix[y*src.Stride : y*src.Stride+width*4]
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = width-1-x, y
+			case 3:
+				dx, dy = width-1-x, height-1-y
+			case 4:
+				dx, dy = x, height-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = height-1-y, x
+			case 7:
+				dx, dy = height-1-y, width-1-x
+			case 8:
+				dx, dy = y, width-1-x
+			}
+			offset := dy*dst.Stride + dx*4
+			copy(dst.Pix[offset:offset+4], row[x*4:x*4+4])
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,213 @@
+package sync
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeImage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 40, 20))); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		data        []byte
+		placeholder string
+		wantWidth   int
+		wantHeight  int
+		wantPrefix  string
+	}{
+		{name: "no placeholder", data: buf.Bytes(), wantWidth: 40, wantHeight: 20},
+		{name: "lqip", data: buf.Bytes(), placeholder: "lqip", wantWidth: 40, wantHeight: 20, wantPrefix: "data:image/png;base64,"},
+		{name: "blurhash", data: buf.Bytes(), placeholder: "blurhash", wantWidth: 40, wantHeight: 20, wantPrefix: "L"},
+		{name: "not an image", data: []byte("<svg></svg>"), placeholder: "lqip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height, lqip := analyzeImage(tt.data, tt.placeholder)
+			if width != tt.wantWidth || height != tt.wantHeight {
+				t.Errorf("analyzeImage() size = %dx%d, want %dx%d", width, height, tt.wantWidth, tt.wantHeight)
+			}
+			if !strings.HasPrefix(lqip, tt.wantPrefix) || (tt.wantPrefix == "") != (lqip == "") {
+				t.Errorf("analyzeImage() placeholder = %q, want a placeholder starting with %q", lqip, tt.wantPrefix)
+			}
+		})
+	}
+}
+
+// exifSegment builds an APP1 segment holding a big-endian TIFF header with an orientation tag
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func TestJpegOrientation(t *testing.T) {
+	soi := []byte{0xFF, 0xD8}
+	app0 := []byte{0xFF, 0xE0, 0x00, 0x04, 0x00, 0x00}
+
+	join := func(parts ...[]byte) []byte {
+		var data []byte
+		for _, part := range parts {
+			data = append(data, part...)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{name: "not a jpeg", data: []byte("\x89PNG\r\n\x1a\n"), want: 1},
+		{name: "no exif", data: join(soi, app0, []byte{0xFF, 0xDA}), want: 1},
+		{name: "exif orientation", data: join(soi, exifSegment(6)), want: 6},
+		{name: "exif after another segment", data: join(soi, app0, exifSegment(8)), want: 8},
+		{name: "invalid orientation", data: join(soi, exifSegment(42)), want: 1},
+		{name: "zero length", data: []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x00}, want: 1},
+		{name: "length of one", data: []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x01, 0xFF, 0xD9}, want: 1},
+		{name: "segment past the end", data: []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x01, 0x00, 'E', 'x'}, want: 1},
+		{name: "truncated length", data: []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00}, want: 1},
+		{name: "fill bytes", data: join(soi, []byte{0xFF, 0xFF, 0xFF}, exifSegment(3)), want: 3},
+		{name: "standalone markers", data: join(soi, []byte{0xFF, 0x01, 0xFF, 0xD0}, exifSegment(5)), want: 5},
+		{name: "garbage between segments", data: join(soi, []byte{0x00}, exifSegment(6)), want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Errorf("jpegOrientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptimizeImageVariantSizes(t *testing.T) {
+	// Noise compresses badly without loss, the lossless WebP variants outweigh the JPEG ones
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 48))
+	random := rand.New(rand.NewSource(1))
+	random.Read(img.Pix)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 50}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := optimizeImage(buf.Bytes(), ImageOptions{Enabled: true, Quality: 50, WebP: true, Widths: []int{32}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, variant := range result.Variants {
+		if variant.Format == "webp" {
+			t.Errorf("kept WebP variant %s, larger than its JPEG rendition", variant.Suffix)
+		}
+	}
+	if len(result.Variants) != 1 {
+		t.Errorf("got %d variants, want the 32w JPEG only", len(result.Variants))
+	}
+	if !result.WebPDropped {
+		t.Error("WebPDropped not set, the dropped variants would go unreported")
+	}
+}
+
+func TestDecodeImageTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+
+	// Claim a 100000x100000 picture in the IHDR chunk, which follows the 8 bytes signature
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 100000)
+	binary.BigEndian.PutUint32(data[20:], 100000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	if _, _, err := decodeImage(data); err == nil {
+		t.Error("decodeImage() decoded a picture above maxImagePixels")
+	}
+}
+
+func TestTiffOrientationOffset(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset uint32
+	}{
+		{name: "past the end", offset: 64},
+		{name: "above 2^31", offset: 0x80000000},
+		{name: "wrapping around", offset: 0xFFFFFFFF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tiff := binary.BigEndian.AppendUint32([]byte("MM\x00\x2a"), tt.offset)
+			if got := tiffOrientation(tiff); got != 1 {
+				t.Errorf("tiffOrientation() = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// A 3x2 picture, each pixel holding its own coordinates
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			src.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), A: 255})
+		}
+	}
+
+	// Rows of the upright picture, as the source coordinates of each of its pixels
+	tests := []struct {
+		orientation int
+		want        [][][2]int
+	}{
+		{orientation: 1, want: [][][2]int{{{0, 0}, {1, 0}, {2, 0}}, {{0, 1}, {1, 1}, {2, 1}}}},
+		{orientation: 2, want: [][][2]int{{{2, 0}, {1, 0}, {0, 0}}, {{2, 1}, {1, 1}, {0, 1}}}},
+		{orientation: 3, want: [][][2]int{{{2, 1}, {1, 1}, {0, 1}}, {{2, 0}, {1, 0}, {0, 0}}}},
+		{orientation: 4, want: [][][2]int{{{0, 1}, {1, 1}, {2, 1}}, {{0, 0}, {1, 0}, {2, 0}}}},
+		{orientation: 5, want: [][][2]int{{{0, 0}, {0, 1}}, {{1, 0}, {1, 1}}, {{2, 0}, {2, 1}}}},
+		{orientation: 6, want: [][][2]int{{{0, 1}, {0, 0}}, {{1, 1}, {1, 0}}, {{2, 1}, {2, 0}}}},
+		{orientation: 7, want: [][][2]int{{{2, 1}, {2, 0}}, {{1, 1}, {1, 0}}, {{0, 1}, {0, 0}}}},
+		{orientation: 8, want: [][][2]int{{{2, 0}, {2, 1}}, {{1, 0}, {1, 1}}, {{0, 0}, {0, 1}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.orientation), func(t *testing.T) {
+			got := applyOrientation(src, tt.orientation)
+			if got.Bounds().Dy() != len(tt.want) || got.Bounds().Dx() != len(tt.want[0]) {
+				t.Fatalf("applyOrientation() size = %v, want %dx%d", got.Bounds().Size(), len(tt.want[0]), len(tt.want))
+			}
+
+			for y, row := range tt.want {
+				for x, from := range row {
+					want := color.NRGBA{R: uint8(from[0]), G: uint8(from[1]), A: 255}
+					if c := color.NRGBAModel.Convert(got.At(x, y)); c != want {
+						t.Errorf("pixel (%d, %d) = %v, want the source pixel %v", x, y, c, from)
+					}
+				}
+			}
+		})
+	}
+}
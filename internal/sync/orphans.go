@@ -0,0 +1,137 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Folders of a page bundle whose files are managed by hugo-notion
+var managedBundleDirs = []string{"images", "files"}
+
+// isKept reports whether a bundle file matches one of the keep patterns. Patterns are matched
+// against the file name and its path relative to the bundle, e.g. "*.svg" or "images/diagram-*".
+func isKept(relPath string, keep []string) bool {
+	for _, pattern := range keep {
+		if ok, _ := filepath.Match(pattern, filepath.Base(relPath)); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.ToSlash(relPath)); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isReferenced reports whether the page content links to a bundle file, by its full name in the
+// folder of the bundle
+func isReferenced(dir string, name string, content string) bool {
+	link := dir + "/" + name
+	for offset := 0; ; {
+		i := strings.Index(content[offset:], link)
+		if i < 0 {
+			return false
+		}
+
+		// "images/a.png" must not match a link to "images/a.png.bak"
+		end := offset + i + len(link)
+		if end == len(content) || !isFilenameChar(content[end]) {
+			return true
+		}
+		offset = end
+	}
+}
+
+// isFilenameChar reports whether c may be part of the name of a bundle file
+func isFilenameChar(c byte) bool {
+	return c == '.' || c == '-' || c == '_' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+// imageFilesReferenced reports whether the named file belongs to an image of which one of the
+// files is referenced by the content, so that the variants of a used image are kept along with it
+func (st *State) imageFilesReferenced(name string, content string) bool {
+	for hash, image := range st.Images {
+		names := imageFileNames(hash, image)
+		if !slices.Contains(names, name) {
+			continue
+		}
+
+		for _, other := range names {
+			if isReferenced("images", other, content) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// cleanupOrphans deletes or reports the files of the page bundle that the content no longer references,
+// depending on the orphaned_files setting ("report", "delete" or "keep")
+func (s *Syncer) cleanupOrphans(postDir string, content string, pageTitle string) {
+	mode := viper.GetString("orphaned_files")
+	if mode == "keep" {
+		return
+	}
+
+	keep := viper.GetStringSlice("orphaned_files_keep")
+
+	for _, dir := range managedBundleDirs {
+		entries, err := os.ReadDir(filepath.Join(postDir, dir))
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+
+			name := entry.Name()
+			relPath := filepath.Join(dir, name)
+			filePath := filepath.Join(postDir, relPath)
+
+			if isReferenced(dir, name, content) || isKept(relPath, keep) {
+				continue
+			}
+			if dir == "images" && s.state != nil && s.state.imageFilesReferenced(name, content) {
+				continue
+			}
+
+			if mode != "delete" {
+				s.addResult(SyncResult{
+					PageTitle:   pageTitle,
+					Status:      "Orphaned",
+					Path:        filePath,
+					LastUpdated: time.Now(),
+					Message:     "file is no longer referenced by the page",
+				})
+				continue
+			}
+
+			if err := os.Remove(filePath); err != nil {
+				s.addResult(SyncResult{
+					PageTitle:   pageTitle,
+					Status:      "Delete Error",
+					Path:        filePath,
+					LastUpdated: time.Now(),
+					Message:     err.Error(),
+				})
+				continue
+			}
+
+			s.addResult(SyncResult{
+				PageTitle:   pageTitle,
+				Status:      "Removed file",
+				Path:        filePath,
+				LastUpdated: time.Now(),
+				Message:     "file was no longer referenced by the page",
+			})
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestIsReferenced(t *testing.T) {
+	tests := []struct {
+		name    string
+		dir     string
+		file    string
+		content string
+		want    bool
+	}{
+		{name: "full name", dir: "images", file: "0123456789abcdef.png", content: "![](/posts/post/images/0123456789abcdef.png)", want: true},
+		{name: "end of content", dir: "files", file: "report.pdf", content: "/posts/post/files/report.pdf", want: true},
+		{name: "hash prefix only", dir: "images", file: "0123456789abcdef-notes.png", content: "![](/posts/post/images/0123456789abcdef.png)", want: false},
+		{name: "longer name", dir: "images", file: "a.png", content: "![](/posts/post/images/a.png.bak)", want: false},
+		{name: "longer name then full name", dir: "images", file: "a.png", content: "images/a.png.bak images/a.png)", want: true},
+		{name: "other folder", dir: "files", file: "a.png", content: "![](/posts/post/images/a.png)", want: false},
+		{name: "not referenced", dir: "files", file: "a.zip", content: "no link", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isReferenced(tt.dir, tt.file, tt.content); got != tt.want {
+				t.Errorf("isReferenced(%q, %q) = %t, want %t", tt.dir, tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanupOrphans(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("orphaned_files", "delete")
+
+	hash := "0123456789abcdef0123456789abcdef"
+	baseName := imageBaseName(hash)
+	postDir := t.TempDir()
+	files := map[string][]string{
+		"images": {baseName + ".png", baseName + "-320w.png", baseName + "-notes.png", "unused.png"},
+		"files":  {baseName + "-report.pdf", baseName + "-old.pdf"},
+	}
+	for dir, names := range files {
+		if err := os.MkdirAll(filepath.Join(postDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			if err := os.WriteFile(filepath.Join(postDir, dir, name), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	state := &State{Images: map[string]*ImageState{
+		hash: {Ext: ".png", Variants: []ImageVariant{{Suffix: "-320w.png", Width: 320, Format: "png"}}},
+	}}
+	s := &Syncer{state: state}
+	content := "![](/posts/post/images/" + baseName + ".png)\n[report](/posts/post/files/" + baseName + "-report.pdf)\n"
+	s.cleanupOrphans(postDir, content, "Post")
+
+	// The variant of the referenced image is kept, the files sharing its hash prefix are not
+	wantRemoved := []string{
+		filepath.Join(postDir, "files", baseName+"-old.pdf"),
+		filepath.Join(postDir, "images", baseName+"-notes.png"),
+		filepath.Join(postDir, "images", "unused.png"),
+	}
+	var removed []string
+	for _, result := range s.results {
+		if result.Status != "Removed file" {
+			t.Errorf("result %s has status %q, want %q", result.Path, result.Status, "Removed file")
+		}
+		removed = append(removed, result.Path)
+	}
+	slices.Sort(removed)
+	if !slices.Equal(removed, wantRemoved) {
+		t.Errorf("removed files = %v, want %v", removed, wantRemoved)
+	}
+
+	for _, kept := range []string{filepath.Join("images", baseName+".png"), filepath.Join("images", baseName+"-320w.png"), filepath.Join("files", baseName+"-report.pdf")} {
+		if _, err := os.Stat(filepath.Join(postDir, kept)); err != nil {
+			t.Errorf("%s was removed: %v", kept, err)
+		}
+	}
+}
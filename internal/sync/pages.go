@@ -0,0 +1,129 @@
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// PageStatus tells how a Notion page compares with its post since the last sync
+type PageStatus string
+
+const (
+	// PageNew pages were never synced, or their post was removed
+	PageNew PageStatus = "new"
+	// PageChanged pages were edited in Notion since the last sync
+	PageChanged PageStatus = "changed"
+	// PageUnchanged pages match their post
+	PageUnchanged PageStatus = "unchanged"
+	// PageLocalModified pages have a post that was edited locally since the last sync
+	PageLocalModified PageStatus = "local modified"
+	// PageDeletedUpstream pages were synced but are no longer in Notion
+	PageDeletedUpstream PageStatus = "deleted upstream"
+)
+
+// contentHash returns the SHA-256 of the content of a post
+func contentHash(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+// postHash returns the hash recorded for a post. When front matter is merged, the keys hugo-notion
+// does not manage are left out, so adding some by hand is not a local modification.
+func postHash(content []byte) string {
+	if viper.GetBool("merge_front_matter") {
+		if managed, ok := managedContent(content); ok {
+			return contentHash(managed)
+		}
+	}
+
+	return contentHash(content)
+}
+
+// matches reports whether a post is the one written by the last sync. Both hashes are accepted, as
+// merge_front_matter may have changed since.
+func (page *PageState) matches(content []byte) bool {
+	return page.Hash == contentHash(content) || page.Hash == postHash(content)
+}
+
+// PageStatus compares a page, as last edited in Notion, with its post. Local modifications come
+// first, since syncing the page would overwrite them.
+func (st *State) PageStatus(pageID string, lastEditedTime time.Time) PageStatus {
+	page, ok := st.Pages[NormalizePageID(pageID)]
+	if !ok {
+		return PageNew
+	}
+
+	content, err := os.ReadFile(page.Path)
+	if err != nil {
+		return PageNew
+	}
+	if !page.matches(content) {
+		return PageLocalModified
+	}
+
+	if lastEditedTime.After(page.LastEditedTime) {
+		return PageChanged
+	}
+
+	return PageUnchanged
+}
+
+// DeletedPages returns the IDs of the synced pages of a parent that are no longer among its children
+func (st *State) DeletedPages(parentID string, childIDs []string) []string {
+	parentID = NormalizePageID(parentID)
+
+	var deleted []string
+	for id, page := range st.Pages {
+		if page.ParentID != parentID {
+			continue
+		}
+		if slices.ContainsFunc(childIDs, func(childID string) bool { return NormalizePageID(childID) == id }) {
+			continue
+		}
+		deleted = append(deleted, id)
+	}
+	slices.Sort(deleted)
+
+	return deleted
+}
+
+// recordPage registers the post written for a page
+func (st *State) recordPage(entry pageEntry, path string, content []byte) {
+	st.Pages[NormalizePageID(entry.ID)] = &PageState{
+		Title:          entry.Title,
+		ParentID:       NormalizePageID(entry.ParentID),
+		Path:           path,
+		LastEditedTime: entry.LastEditedTime,
+		Hash:           postHash(content),
+		SourceHash:     postHash(content),
+	}
+}
+
+// forgetPages drops the pages whose post was in a deleted directory
+func (st *State) forgetPages(dir string) {
+	for id, page := range st.Pages {
+		if page.Path == dir || strings.HasPrefix(page.Path, dir+string(filepath.Separator)) {
+			delete(st.Pages, id)
+		}
+	}
+}
+
+// refreshPages records the current content of the posts in a directory, rewritten by hugo-notion
+// itself, so that the change is not taken for a local modification
+func (st *State) refreshPages(dir string) {
+	for _, page := range st.Pages {
+		if page.Path != dir && !strings.HasPrefix(page.Path, dir+string(filepath.Separator)) {
+			continue
+		}
+		if content, err := os.ReadFile(page.Path); err == nil {
+			page.Hash = postHash(content)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPageStatus(t *testing.T) {
+	synced := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	state := &State{Pages: map[string]*PageState{}}
+	state.recordPage(pageEntry{ID: "11111111-1111-1111-1111-111111111111", LastEditedTime: synced}, write("synced.md", "post"), []byte("post"))
+	state.recordPage(pageEntry{ID: "22222222-2222-2222-2222-222222222222", LastEditedTime: synced}, write("edited.md", "edited post"), []byte("post"))
+	state.recordPage(pageEntry{ID: "33333333-3333-3333-3333-333333333333", LastEditedTime: synced}, filepath.Join(dir, "removed.md"), []byte("post"))
+
+	tests := []struct {
+		name           string
+		id             string
+		lastEditedTime time.Time
+		want           PageStatus
+	}{
+		{
+			name:           "never synced",
+			id:             "44444444444444444444444444444444",
+			lastEditedTime: synced,
+			want:           PageNew,
+		},
+		{
+			name:           "unchanged",
+			id:             "11111111111111111111111111111111",
+			lastEditedTime: synced,
+			want:           PageUnchanged,
+		},
+		{
+			name:           "edited in Notion",
+			id:             "11111111-1111-1111-1111-111111111111",
+			lastEditedTime: synced.Add(time.Minute),
+			want:           PageChanged,
+		},
+		{
+			name:           "edited locally and in Notion",
+			id:             "22222222222222222222222222222222",
+			lastEditedTime: synced.Add(time.Minute),
+			want:           PageLocalModified,
+		},
+		{
+			name:           "post removed",
+			id:             "33333333333333333333333333333333",
+			lastEditedTime: synced,
+			want:           PageNew,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := state.PageStatus(tt.id, tt.lastEditedTime); got != tt.want {
+				t.Errorf("PageStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeletedPages(t *testing.T) {
+	const parentID = "00000000-0000-0000-0000-000000000000"
+
+	state := &State{Pages: map[string]*PageState{}}
+	for _, entry := range []pageEntry{
+		{ID: "11111111111111111111111111111111", ParentID: parentID},
+		{ID: "22222222222222222222222222222222", ParentID: parentID},
+		{ID: "33333333333333333333333333333333", ParentID: parentID},
+		{ID: "44444444444444444444444444444444", ParentID: "55555555555555555555555555555555"},
+	} {
+		state.recordPage(entry, "post.md", nil)
+	}
+
+	got := state.DeletedPages("00000000000000000000000000000000", []string{"22222222-2222-2222-2222-222222222222"})
+	want := []string{"11111111111111111111111111111111", "33333333333333333333333333333333"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DeletedPages() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,52 @@
+package sync
+
+import "time"
+
+// Progress tells how far a sync has gone. The total grows as the databases are listed.
+type Progress struct {
+	// Total is the number of pages to sync found so far
+	Total int
+	// Done is the number of pages synced, whatever their result
+	Done int
+	// Current is the title of the page being synced
+	Current string
+	// ImageBytes is the size of the images downloaded
+	ImageBytes int64
+	Started    time.Time
+}
+
+// ReportProgress makes the syncer send its progress to a channel, each time it changes
+func (s *Syncer) ReportProgress(progress chan<- Progress) {
+	s.progressUpdates = progress
+}
+
+// addPagesToSync counts pages found to sync in the total
+func (s *Syncer) addPagesToSync(count int) {
+	s.progress.Total += count
+	s.sendProgress()
+}
+
+// startPage marks a page as being synced
+func (s *Syncer) startPage(title string) {
+	s.progress.Current = title
+	s.sendProgress()
+}
+
+// finishPage marks the page being synced as done
+func (s *Syncer) finishPage() {
+	s.progress.Done++
+	s.progress.Current = ""
+	s.sendProgress()
+}
+
+// addImageBytes counts downloaded image data
+func (s *Syncer) addImageBytes(size int) {
+	s.progress.ImageBytes += int64(size)
+	s.sendProgress()
+}
+
+func (s *Syncer) sendProgress() {
+	if s.progressUpdates != nil {
+		s.progressUpdates <- s.progress
+	}
+}
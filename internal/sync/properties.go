@@ -0,0 +1,28 @@
+package sync
+
+import (
+	"strings"
+
+	"github.com/jomei/notionapi"
+)
+
+// PageTitle returns the title of a database row, held by its title property
+func PageTitle(notionPage *notionapi.Page) string {
+	for _, property := range notionPage.Properties {
+		if title, ok := property.(*notionapi.TitleProperty); ok {
+			return richTextValue(title.Title)
+		}
+	}
+
+	return ""
+}
+
+// richTextValue returns the plain text of a rich text
+func richTextValue(texts []notionapi.RichText) string {
+	var text strings.Builder
+	for _, t := range texts {
+		text.WriteString(t.PlainText)
+	}
+
+	return text.String()
+}
@@ -0,0 +1,66 @@
+package sync
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jomei/notionapi"
+	"github.com/spf13/viper"
+)
+
+// PublishState tells what to do with a page according to its publish property
+type PublishState int
+
+const (
+	// Published pages are written as is
+	Published PublishState = iota
+	// Draft pages are written with "draft: true"
+	Draft
+	// Unpublished pages are not written, and their existing post is deleted or drafted
+	Unpublished
+)
+
+// pagePublishState reads the publish_property of a page, which may be a Status, Select or Checkbox
+// property. Values listed in publish_values publish the page, values listed in draft_values make it
+// a draft and any other value unpublishes it. Checkboxes read as "true" or "false", and are
+// published when checked unless publish_values says otherwise.
+// Pages without the property, such as pages outside of a database, are published.
+func pagePublishState(notionPage *notionapi.Page) PublishState {
+	propertyName := viper.GetString("publish_property")
+	if propertyName == "" || notionPage == nil {
+		return Published
+	}
+
+	var value string
+	publishValues := viper.GetStringSlice("publish_values")
+
+	switch property := notionPage.Properties[propertyName].(type) {
+	case *notionapi.StatusProperty:
+		value = property.Status.Name
+	case *notionapi.SelectProperty:
+		value = property.Select.Name
+	case *notionapi.CheckboxProperty:
+		value = strconv.FormatBool(property.Checkbox)
+		if len(publishValues) == 0 {
+			publishValues = []string{"true"}
+		}
+	default:
+		return Published
+	}
+
+	matches := func(values []string) bool {
+		return slices.ContainsFunc(values, func(v string) bool {
+			return strings.EqualFold(strings.TrimSpace(v), value)
+		})
+	}
+
+	switch {
+	case matches(publishValues):
+		return Published
+	case matches(viper.GetStringSlice("draft_values")):
+		return Draft
+	default:
+		return Unpublished
+	}
+}
@@ -0,0 +1,116 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/jomei/notionapi"
+	"github.com/spf13/viper"
+)
+
+func TestPagePublishState(t *testing.T) {
+	// A database row, with its properties as returned by the API
+	row := &notionapi.Page{
+		Properties: notionapi.Properties{
+			"Name":   &notionapi.TitleProperty{Title: []notionapi.RichText{{PlainText: "Post"}}},
+			"Status": &notionapi.StatusProperty{Status: notionapi.Status{Name: "In review"}},
+			"Stage":  &notionapi.SelectProperty{Select: notionapi.Option{Name: "Published"}},
+			"Ready":  &notionapi.CheckboxProperty{Checkbox: true},
+			"Hidden": &notionapi.CheckboxProperty{Checkbox: false},
+			"Notes":  &notionapi.RichTextProperty{RichText: []notionapi.RichText{{PlainText: "Published"}}},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		property      string
+		publishValues []string
+		draftValues   []string
+		page          *notionapi.Page
+		want          PublishState
+	}{
+		{
+			name: "no publish property",
+			page: row,
+			want: Published,
+		},
+		{
+			name:     "page outside of a database",
+			property: "Status",
+			want:     Published,
+		},
+		{
+			name:     "missing property",
+			property: "Missing",
+			page:     row,
+			want:     Published,
+		},
+		{
+			name:     "unsupported property type",
+			property: "Notes",
+			page:     row,
+			want:     Published,
+		},
+		{
+			name:          "status listed in draft_values",
+			property:      "Status",
+			publishValues: []string{"Published"},
+			draftValues:   []string{" in review "},
+			page:          row,
+			want:          Draft,
+		},
+		{
+			name:          "status not listed",
+			property:      "Status",
+			publishValues: []string{"Published"},
+			page:          row,
+			want:          Unpublished,
+		},
+		{
+			name:          "select listed in publish_values",
+			property:      "Stage",
+			publishValues: []string{"published"},
+			page:          row,
+			want:          Published,
+		},
+		{
+			name:     "checked checkbox",
+			property: "Ready",
+			page:     row,
+			want:     Published,
+		},
+		{
+			name:     "unchecked checkbox",
+			property: "Hidden",
+			page:     row,
+			want:     Unpublished,
+		},
+		{
+			name:        "unchecked checkbox as draft",
+			property:    "Hidden",
+			draftValues: []string{"false"},
+			page:        row,
+			want:        Draft,
+		},
+		{
+			name:          "checkbox with publish_values",
+			property:      "Ready",
+			publishValues: []string{"false"},
+			page:          row,
+			want:          Unpublished,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.Set("publish_property", tt.property)
+			viper.Set("publish_values", tt.publishValues)
+			viper.Set("draft_values", tt.draftValues)
+
+			if got := pagePublishState(tt.page); got != tt.want {
+				t.Errorf("pagePublishState() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package sync
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// S3ImageStore uploads images to a bucket of an S3-compatible object storage (AWS S3, MinIO, R2...).
+// Objects are named after the image content hash, so identical images are shared between pages.
+type S3ImageStore struct {
+	// Endpoint is the base URL of the storage API, e.g. https://s3.eu-west-1.amazonaws.com or http://localhost:9000
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+	// PublicURL is the base URL images are served from, such as a CDN. Defaults to the bucket URL.
+	PublicURL string
+	// PathStyle addresses the bucket in the path rather than in the host name, as MinIO expects
+	PathStyle bool
+
+	client *http.Client
+	known  map[string]bool
+}
+
+func NewS3ImageStore(endpoint, region, bucket, accessKey, secretKey string) *S3ImageStore {
+	return &S3ImageStore{
+		Endpoint:  strings.TrimRight(endpoint, "/"),
+		Region:    region,
+		Bucket:    bucket,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		client:    &http.Client{Timeout: 2 * time.Minute},
+		known:     make(map[string]bool),
+	}
+}
+
+// NewS3ImageStoreFromConfig builds the store from the s3_* settings, falling back on the
+// standard AWS environment variables for the credentials
+func NewS3ImageStoreFromConfig() (*S3ImageStore, error) {
+	accessKey := viper.GetString("s3_access_key")
+	if accessKey == "" {
+		accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+
+	secretKey := viper.GetString("s3_secret_key")
+	if secretKey == "" {
+		secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+
+	region := viper.GetString("s3_region")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	endpoint := viper.GetString("s3_endpoint")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+
+	bucket := viper.GetString("s3_bucket")
+	if bucket == "" {
+		return nil, fmt.Errorf("s3_bucket is required to store images on S3")
+	}
+
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("S3 credentials not provided")
+	}
+
+	store := NewS3ImageStore(endpoint, region, bucket, accessKey, secretKey)
+	store.Prefix = strings.Trim(viper.GetString("s3_prefix"), "/")
+	store.PublicURL = strings.TrimRight(viper.GetString("s3_public_url"), "/")
+	store.PathStyle = viper.GetBool("s3_path_style")
+
+	return store, nil
+}
+
+func (s3 *S3ImageStore) Has(_ PageLocation, name string) bool {
+	key := s3.key(name)
+	if s3.known[key] {
+		return true
+	}
+
+	req, err := http.NewRequest(http.MethodHead, s3.objectURL(key), nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := s3.do(req, nil)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	s3.known[key] = resp.StatusCode == http.StatusOK
+	return s3.known[key]
+}
+
+func (s3 *S3ImageStore) Put(_ PageLocation, name string, data []byte) error {
+	key := s3.key(name)
+
+	req, err := http.NewRequest(http.MethodPut, s3.objectURL(key), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	req.Header.Set("Content-Type", contentType)
+	// Names are content hashes, the objects never change
+	req.Header.Set("Cache-Control", "public, max-age=31536000, immutable")
+
+	resp, err := s3.do(req, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("upload of %s failed: %s: %s", key, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	s3.known[key] = true
+	return nil
+}
+
+func (s3 *S3ImageStore) URL(_ PageLocation, name string) string {
+	key := s3.key(name)
+	if s3.PublicURL != "" {
+		return s3.PublicURL + "/" + escapeS3Path(key)
+	}
+
+	return s3.objectURL(key)
+}
+
+func (s3 *S3ImageStore) key(name string) string {
+	if s3.Prefix == "" {
+		return name
+	}
+
+	return s3.Prefix + "/" + name
+}
+
+// objectURL returns the API URL of an object, in path or virtual-hosted style
+func (s3 *S3ImageStore) objectURL(key string) string {
+	if s3.PathStyle {
+		return fmt.Sprintf("%s/%s/%s", s3.Endpoint, s3.Bucket, escapeS3Path(key))
+	}
+
+	endpoint, err := url.Parse(s3.Endpoint)
+	if err != nil {
+		return fmt.Sprintf("%s/%s/%s", s3.Endpoint, s3.Bucket, escapeS3Path(key))
+	}
+
+	return fmt.Sprintf("%s://%s.%s/%s", endpoint.Scheme, s3.Bucket, endpoint.Host, escapeS3Path(key))
+}
+
+// do signs the request with AWS Signature Version 4 and sends it
+func (s3 *S3ImageStore) do(req *http.Request, payload []byte) (*http.Response, error) {
+	signS3Request(req, payload, s3.Region, s3.AccessKey, s3.SecretKey, time.Now().UTC())
+	return s3.client.Do(req)
+}
+
+// signS3Request adds the AWS Signature Version 4 headers to the request.
+// The host, the x-amz-* headers and the content headers are signed.
+func signS3Request(req *http.Request, payload []byte, region, accessKey, secretKey string, now time.Time) {
+	payloadSum := sha256.Sum256(payload)
+	payloadHash := hex.EncodeToString(payloadSum[:])
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-amz-") || name == "content-type" || name == "content-md5" || name == "range" {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalS3Query(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, region)
+	requestSum := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestSum[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalS3Query sorts and encodes query parameters as SigV4 expects
+func canonicalS3Query(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var params []string
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			params = append(params, escapeS3(key)+"="+escapeS3(value))
+		}
+	}
+
+	return strings.Join(params, "&")
+}
+
+// escapeS3Path escapes every segment of an object key, keeping the slashes
+func escapeS3Path(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = escapeS3(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// escapeS3 percent-encodes everything but the RFC 3986 unreserved characters
+func escapeS3(value string) string {
+	var escaped strings.Builder
+	for _, b := range []byte(value) {
+		if ('A' <= b && b <= 'Z') || ('a' <= b && b <= 'z') || ('0' <= b && b <= '9') || strings.IndexByte("-_.~", b) >= 0 {
+			escaped.WriteByte(b)
+			continue
+		}
+		fmt.Fprintf(&escaped, "%%%02X", b)
+	}
+
+	return escaped.String()
+}
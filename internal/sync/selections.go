@@ -0,0 +1,71 @@
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const selectionsFileName = "selections.json"
+
+// Selections are the page selections saved in the state directory
+type Selections struct {
+	// Last is the selection of the last interactive run, pre-checked by the next one
+	Last []string `json:"last"`
+	// Named maps the name of a saved selection to its page IDs
+	Named map[string][]string `json:"named"`
+
+	path string
+}
+
+// LoadSelections reads the selections file from stateDir. A missing file yields no selection.
+func LoadSelections(stateDir string) (*Selections, error) {
+	selections := &Selections{
+		Named: make(map[string][]string),
+		path:  filepath.Join(stateDir, selectionsFileName),
+	}
+
+	data, err := os.ReadFile(selections.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return selections, nil
+	}
+	if err != nil {
+		return selections, err
+	}
+
+	if err := json.Unmarshal(data, selections); err != nil {
+		return selections, err
+	}
+
+	if selections.Named == nil {
+		selections.Named = make(map[string][]string)
+	}
+
+	return selections, nil
+}
+
+// Get returns the pages of a named selection
+func (sel *Selections) Get(name string) ([]string, error) {
+	pages, ok := sel.Named[name]
+	if !ok || len(pages) == 0 {
+		return nil, fmt.Errorf("no selection named %q in %s", name, sel.path)
+	}
+
+	return pages, nil
+}
+
+// Save writes the selections back to the file they were loaded from
+func (sel *Selections) Save() error {
+	if err := os.MkdirAll(filepath.Dir(sel.path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(sel, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(sel.path, data, 0644)
+}
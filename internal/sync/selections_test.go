@@ -0,0 +1,102 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadSelections(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantLast  []string
+		wantNamed map[string][]string
+		wantErr   bool
+	}{
+		{name: "missing file", wantNamed: map[string][]string{}},
+		{
+			name:      "saved selections",
+			content:   `{"last":["a"],"named":{"newsletter":["b","c"]}}`,
+			wantLast:  []string{"a"},
+			wantNamed: map[string][]string{"newsletter": {"b", "c"}},
+		},
+		{name: "no named selection", content: `{"last":["a"],"named":null}`, wantLast: []string{"a"}, wantNamed: map[string][]string{}},
+		{name: "invalid file", content: `{"last":`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stateDir := t.TempDir()
+			if tt.content != "" {
+				if err := os.WriteFile(filepath.Join(stateDir, selectionsFileName), []byte(tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			selections, err := LoadSelections(stateDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadSelections() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(selections.Last, tt.wantLast) || !reflect.DeepEqual(selections.Named, tt.wantNamed) {
+				t.Errorf("LoadSelections() = %v %v, want %v %v", selections.Last, selections.Named, tt.wantLast, tt.wantNamed)
+			}
+		})
+	}
+}
+
+func TestSelectionsSave(t *testing.T) {
+	// The state directory is created on the first save
+	stateDir := filepath.Join(t.TempDir(), ".hugo-notion")
+
+	selections, err := LoadSelections(stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	selections.Last = []string{"a", "b"}
+	selections.Named["newsletter"] = []string{"c"}
+	if err := selections.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadSelections(stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Last, selections.Last) || !reflect.DeepEqual(loaded.Named, selections.Named) {
+		t.Errorf("saved selections read back as %v %v, want %v %v", loaded.Last, loaded.Named, selections.Last, selections.Named)
+	}
+}
+
+func TestSelectionsGet(t *testing.T) {
+	selections := &Selections{Named: map[string][]string{
+		"newsletter": {"a", "b"},
+		"empty":      {},
+	}}
+
+	tests := []struct {
+		name    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "newsletter", want: []string{"a", "b"}},
+		{name: "empty", wantErr: true},
+		{name: "unknown", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selections.Get(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get(%q) error = %v, wantErr %t", tt.name, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const stateFileName = "state.json"
+
+// State is the persistent record of what previous syncs produced
+type State struct {
+	// Images maps the content hash of every stored image to where it lives on disk
+	Images map[string]*ImageState `json:"images"`
+	// Sources maps the stable key of an image URL to the content hash it resolved to
+	Sources map[string]string `json:"sources"`
+	// Assets maps the content hash of every downloaded attachment to where it lives on disk
+	Assets map[string]*AssetState `json:"assets"`
+	// Pages maps the ID of every synced page to the post written for it
+	Pages map[string]*PageState `json:"pages"`
+
+	path string
+}
+
+// ImageState tracks the local copies of an image stored by content hash
+type ImageState struct {
+	Ext    string `json:"ext"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+	// Placeholder is the BlurHash or base64 LQIP of the image, depending on the configuration
+	Placeholder string         `json:"placeholder,omitempty"`
+	Profile     string         `json:"profile,omitempty"`
+	Variants    []ImageVariant `json:"variants,omitempty"`
+	// WebPDropped is set when the WebP variants were larger than the renditions they stand in for
+	WebPDropped bool     `json:"webp_dropped,omitempty"`
+	Files       []string `json:"files"`
+}
+
+// ImageVariant is an additional rendition of an image, named after it with Suffix appended
+type ImageVariant struct {
+	Suffix string `json:"suffix"`
+	Width  int    `json:"width"`
+	Format string `json:"format"`
+}
+
+// AssetState tracks the local copies of an attachment stored by content hash
+type AssetState struct {
+	Name  string   `json:"name"`
+	Files []string `json:"files"`
+}
+
+// PageState tracks the post written for a page by the last sync
+type PageState struct {
+	Title    string `json:"title"`
+	ParentID string `json:"parent_id"`
+	Path     string `json:"path"`
+	// LastEditedTime is the last edition of the page when it was synced
+	LastEditedTime time.Time `json:"last_edited_time"`
+	// Hash is the SHA-256 of the written file, to detect local modifications
+	Hash string `json:"hash"`
+	// SourceHash is the SHA-256 of the post generated from Notion, which differs from Hash when
+	// local modifications were kept
+	SourceHash string `json:"source_hash,omitempty"`
+}
+
+// LoadState reads the state file from stateDir. A missing file yields an empty state.
+func LoadState(stateDir string) (*State, error) {
+	state := &State{
+		Images:  make(map[string]*ImageState),
+		Sources: make(map[string]string),
+		Assets:  make(map[string]*AssetState),
+		Pages:   make(map[string]*PageState),
+		path:    filepath.Join(stateDir, stateFileName),
+	}
+
+	data, err := os.ReadFile(state.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return state, err
+	}
+
+	if state.Images == nil {
+		state.Images = make(map[string]*ImageState)
+	}
+	if state.Sources == nil {
+		state.Sources = make(map[string]string)
+	}
+	if state.Assets == nil {
+		state.Assets = make(map[string]*AssetState)
+	}
+	if state.Pages == nil {
+		state.Pages = make(map[string]*PageState)
+	}
+
+	return state, nil
+}
+
+// Save writes the state back to the file it was loaded from
+func (st *State) Save() error {
+	if err := os.MkdirAll(filepath.Dir(st.path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(st.path, data, 0644)
+}
+
+// imageFile returns an existing local copy of the image with the given hash, if any
+func (st *State) imageFile(hash string) (string, bool) {
+	image, ok := st.Images[hash]
+	if !ok {
+		return "", false
+	}
+
+	return existingFile(image.Files)
+}
+
+// assetFile returns an existing local copy of the attachment with the given hash, if any
+func (st *State) assetFile(hash string) (string, bool) {
+	asset, ok := st.Assets[hash]
+	if !ok {
+		return "", false
+	}
+
+	return existingFile(asset.Files)
+}
+
+// imageByName finds the image a stored file, named after the image hash, belongs to
+func (st *State) imageByName(name string) (string, *ImageState, bool) {
+	for hash, image := range st.Images {
+		if strings.HasPrefix(name, imageBaseName(hash)) {
+			return hash, image, true
+		}
+	}
+
+	return "", nil, false
+}
+
+// localCopy returns an existing local copy of the named image file, if any
+func (st *State) localCopy(name string) (string, bool) {
+	hash, _, ok := st.imageByName(name)
+	if !ok {
+		return "", false
+	}
+
+	file, ok := st.imageFile(hash)
+	if !ok {
+		return "", false
+	}
+
+	candidate := filepath.Join(filepath.Dir(file), name)
+	if _, err := os.Stat(candidate); err != nil {
+		return "", false
+	}
+
+	return candidate, true
+}
+
+// setImage replaces what is known about the image with the given hash, keeping its local copies
+func (st *State) setImage(hash string, image *ImageState) {
+	if previous, ok := st.Images[hash]; ok {
+		image.Files = previous.Files
+	}
+	st.Images[hash] = image
+}
+
+// recordImage registers a local copy of the image with the given hash
+func (st *State) recordImage(hash string, file string) {
+	if image, ok := st.Images[hash]; ok {
+		image.Files = addFile(image.Files, file)
+	}
+}
+
+// recordAsset registers a local copy of the attachment with the given hash
+func (st *State) recordAsset(hash string, file string) {
+	if asset, ok := st.Assets[hash]; ok {
+		asset.Files = addFile(asset.Files, file)
+	}
+}
+
+// existingFile returns the first of the files that is still on disk
+func existingFile(files []string) (string, bool) {
+	for _, file := range files {
+		if _, err := os.Stat(file); err == nil {
+			return file, true
+		}
+	}
+
+	return "", false
+}
+
+// addFile adds a file to the list, dropping those removed since the last sync
+func addFile(files []string, file string) []string {
+	files = slices.DeleteFunc(files, func(f string) bool {
+		_, err := os.Stat(f)
+		return err != nil
+	})
+
+	if !slices.Contains(files, file) {
+		files = append(files, file)
+	}
+
+	return files
+}
@@ -0,0 +1,85 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ImageStore persists the image files of pages and tells where they are served from
+type ImageStore interface {
+	// Has reports whether the named image file is already stored for the page
+	Has(page PageLocation, name string) bool
+	// Put stores the named image file for the page, replacing any previous version
+	Put(page PageLocation, name string, data []byte) error
+	// URL returns the public URL of the named image file of the page
+	URL(page PageLocation, name string) string
+}
+
+// PageLocation locates the Hugo page bundle an image belongs to
+type PageLocation struct {
+	// Dir is the directory of the page bundle
+	Dir string
+	// Slug is the name of the page bundle in the site URLs
+	Slug string
+}
+
+// newImageStoreFromConfig returns the image store selected by the configuration
+func newImageStoreFromConfig(state *State) (ImageStore, error) {
+	if viper.GetBool("s3_images") {
+		return NewS3ImageStoreFromConfig()
+	}
+
+	return NewLocalImageStore(viper.GetString("posts_base_uri"), state), nil
+}
+
+// LocalImageStore keeps images in the "images" folder of each page bundle.
+// Identical files are hard linked between bundles when the filesystem allows it.
+type LocalImageStore struct {
+	baseURI string
+	state   *State
+}
+
+func NewLocalImageStore(baseURI string, state *State) *LocalImageStore {
+	return &LocalImageStore{
+		baseURI: strings.TrimRight(baseURI, "/"),
+		state:   state,
+	}
+}
+
+func (l *LocalImageStore) Has(page PageLocation, name string) bool {
+	_, err := os.Stat(filepath.Join(page.Dir, "images", name))
+	return err == nil
+}
+
+func (l *LocalImageStore) Put(page PageLocation, name string, data []byte) error {
+	imagesDir := filepath.Join(page.Dir, "images")
+	if err := os.MkdirAll(imagesDir, 0755); err != nil {
+		return err
+	}
+
+	imagePath := filepath.Join(imagesDir, name)
+	linked := false
+	if existing, ok := l.state.localCopy(name); ok && existing != imagePath {
+		linked = os.Link(existing, imagePath) == nil
+	}
+
+	if !linked {
+		if err := os.WriteFile(imagePath, data, 0644); err != nil {
+			return err
+		}
+	}
+
+	if hash, image, ok := l.state.imageByName(name); ok && name == imageBaseName(hash)+image.Ext {
+		l.state.recordImage(hash, imagePath)
+	}
+
+	return nil
+}
+
+func (l *LocalImageStore) URL(page PageLocation, name string) string {
+	return fmt.Sprintf("%s/%s/images/%s", l.baseURI, page.Slug, name)
+}
@@ -3,17 +3,10 @@ package sync
 import (
 	"bytes"
 	"context"
-	"crypto/sha256"
 	"fmt"
-	"github.com/ma111e/notion2markdown"
 	"github.com/spf13/viper"
-	"io"
-	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
-	"regexp"
-	"slices"
 	"strings"
 	"time"
 
@@ -22,22 +15,38 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
-// Regular expression to find Markdown image tags
-var imageRegex = regexp.MustCompile(`!\[(.*?)\]\((.*?)\)`)
-
 type SyncResult struct {
 	PageTitle   string
 	Status      string
 	Path        string
 	LastUpdated time.Time
+	Message     string
+	// PageID is the Notion page the result is about, if any
+	PageID string
+	// Previous is the former content of updated posts, and the local version of posts in conflict
+	Previous string
+	// Incoming is the version generated from Notion of a post in conflict, when it was not written
+	// to Path
+	Incoming string
 }
 
 type Syncer struct {
-	client        *notionapi.Client
-	contentDir    string
-	results       []SyncResult
-	selectedPages []string
-	updates       chan<- SyncResult // Channel for live updates
+	client          *notionapi.Client
+	contentDir      string
+	filter          *PageFilter
+	results         []SyncResult
+	selectedPages   []string
+	foundPages      map[string]bool   // Selected pages found under the root page
+	updates         chan<- SyncResult // Channel for live updates
+	state           *State
+	images          ImageStore
+	markdownCache   *MarkdownCache
+	currentPageID   string // Page being synced, which results are attributed to
+	stateDir        string
+	trashRun        string   // Trash folder of the posts deleted by this sync
+	unpublishedDirs []string // Posts of unpublished pages, deleted at the end of the sync
+	progress        Progress
+	progressUpdates chan<- Progress // Channel for progress updates
 }
 
 func NewSyncer(client *notionapi.Client, contentDir string) *Syncer {
@@ -60,23 +69,153 @@ func NewSyncerWithSelection(client *notionapi.Client, contentDir string, selecte
 
 func (s *Syncer) Sync(pageID string) []SyncResult {
 	s.results = make([]SyncResult, 0)
+	s.foundPages = make(map[string]bool)
+	s.progress = Progress{Started: time.Now()}
+
+	stateDir := viper.GetString("state_dir")
+	s.stateDir = stateDir
+	s.trashRun = ""
+	s.unpublishedDirs = nil
+	state, err := LoadState(stateDir)
+	if err != nil {
+		s.addResult(SyncResult{
+			PageTitle:   "State",
+			Status:      "Error",
+			Path:        stateDir,
+			LastUpdated: time.Now(),
+			Message:     err.Error(),
+		})
+	}
+	s.state = state
+
+	images, err := newImageStoreFromConfig(s.state)
+	if err != nil {
+		s.addResult(SyncResult{
+			PageTitle:   "Image Store",
+			Status:      "Error",
+			Path:        s.contentDir,
+			LastUpdated: time.Now(),
+			Message:     err.Error(),
+		})
+		return s.results
+	}
+	s.images = images
+
+	filter, err := LoadPageFilter()
+	if err != nil {
+		s.addResult(SyncResult{
+			PageTitle:   "Filters",
+			Status:      "Error",
+			Path:        s.contentDir,
+			LastUpdated: time.Now(),
+			Message:     err.Error(),
+		})
+		return s.results
+	}
+	s.filter = filter
+
 	s.syncPage(pageID, s.contentDir)
+
+	if s.trashRun != "" {
+		if _, err := PruneTrash(stateDir, viper.GetInt("trash_retention")); err != nil {
+			s.addResult(SyncResult{
+				PageTitle:   "Trash",
+				Status:      "Error",
+				Path:        filepath.Join(stateDir, trashDirName),
+				LastUpdated: time.Now(),
+				Message:     err.Error(),
+			})
+		}
+	}
+
+	if err := s.state.Save(); err != nil {
+		s.addResult(SyncResult{
+			PageTitle:   "State",
+			Status:      "Error",
+			Path:        stateDir,
+			LastUpdated: time.Now(),
+			Message:     err.Error(),
+		})
+	}
+
 	return s.results
 }
 
-func (s *Syncer) syncPage(pageIDString string, hugoPageDir string) {
-	pageID := notionapi.BlockID(pageIDString)
-	pagination := notionapi.Pagination{
-		PageSize: 100,
+// pageEntry is a page written as a post: a child page of the root page or a row of one of its databases
+type pageEntry struct {
+	ID             string
+	Title          string
+	ParentID       string
+	CreatedTime    time.Time
+	LastEditedTime time.Time
+	// Page is already known for database rows, and fetched for child pages
+	Page *notionapi.Page
+}
+
+// isSelected reports whether a page was selected, or if every page is synced
+func (s *Syncer) isSelected(id string) bool {
+	if len(s.selectedPages) == 0 {
+		return true
+	}
+
+	id = NormalizePageID(id)
+	for _, selected := range s.selectedPages {
+		if NormalizePageID(selected) == id {
+			s.foundPages[id] = true
+			return true
+		}
+	}
+
+	return false
+}
+
+// reportMissingPages warns about the selected pages not found under the root page, such as pages
+// of a saved selection deleted since, and about a selection of which no page was found at all
+func (s *Syncer) reportMissingPages(hugoPageDir string) {
+	missing := 0
+	for _, selected := range s.selectedPages {
+		if !s.foundPages[NormalizePageID(selected)] {
+			missing++
+			s.addResult(SyncResult{
+				PageTitle:   selected,
+				Status:      "Warning",
+				Path:        hugoPageDir,
+				LastUpdated: time.Now(),
+				Message:     "selected page not found under the root page",
+			})
+		}
+	}
+
+	if missing > 0 && missing == len(s.selectedPages) {
+		s.addResult(SyncResult{
+			PageTitle:   "Selection",
+			Status:      "Warning",
+			Path:        hugoPageDir,
+			LastUpdated: time.Now(),
+			Message:     fmt.Sprintf("none of the %d selected pages was found under the root page, nothing was synced", missing),
+		})
 	}
+}
+
+// pageSlug returns the name of the directory and file of the post of a page
+func pageSlug(pageTitle string) string {
+	return strings.ReplaceAll(strings.ToLower(pageTitle), " ", "_")
+}
+
+// pageBundleDir returns the directory of the page bundle of a page
+func pageBundleDir(hugoPageDir string, pageTitle string) string {
+	return filepath.Join(hugoPageDir, pageSlug(pageTitle))
+}
 
-	getChildrenResponse, err := s.client.Block.GetChildren(context.Background(), pageID, &pagination)
+func (s *Syncer) syncPage(pageIDString string, hugoPageDir string) {
+	children, err := s.fetchBlocks(pageIDString)
 	if err != nil {
 		s.addResult(SyncResult{
 			PageTitle:   "Root Page",
 			Status:      "Error",
 			Path:        hugoPageDir,
 			LastUpdated: time.Now(),
+			Message:     err.Error(),
 		})
 		return
 	}
@@ -96,152 +235,238 @@ func (s *Syncer) syncPage(pageIDString string, hugoPageDir string) {
 	}
 
 	for _, entry := range entries {
-		if entry.IsDir() {
-			dirPath := filepath.Join(hugoPageDir, entry.Name())
-			existingHugoPageDirs = append(existingHugoPageDirs, dirPath)
+		// Hidden folders and the state directory, which may be in the content directory, are no posts
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
 		}
-	}
 
-	for _, _block := range getChildrenResponse.Results {
-		blockID := string(_block.GetID())
-
-		// Skip if not selected (when in selective mode)
-		if len(s.selectedPages) > 0 && !slices.Contains(s.selectedPages, blockID) {
+		dirPath := filepath.Join(hugoPageDir, entry.Name())
+		if containsPath(dirPath, s.stateDir) {
 			continue
 		}
-
-		s.syncChildPage(_block.(*notionapi.ChildPageBlock), hugoPageDir, syncTime, &syncedHugoPageDirs)
+		existingHugoPageDirs = append(existingHugoPageDirs, dirPath)
 	}
 
-	// Only delete files in full sync mode
-	if len(s.selectedPages) == 0 {
-		// Clean up old directories
-		oldHugoPageDirs, _ := lo.Difference(existingHugoPageDirs, syncedHugoPageDirs)
-		s.deleteDirectories(oldHugoPageDirs)
+	// Incomplete syncs must not delete the posts of the pages they could not list
+	complete := true
+
+	// List all the pages first, for the progress to know how many there are
+	var pendingEntries []pageEntry
+	for _, _block := range children {
+		switch block := _block.(type) {
+		case *notionapi.ChildPageBlock:
+			entry := pageEntry{
+				ID:       string(block.ID),
+				Title:    block.ChildPage.Title,
+				ParentID: pageIDString,
+			}
+			if block.CreatedTime != nil {
+				entry.CreatedTime = *block.CreatedTime
+			}
+			if block.LastEditedTime != nil {
+				entry.LastEditedTime = *block.LastEditedTime
+			}
+
+			// Skip if not selected (when in selective mode)
+			if !s.isSelected(entry.ID) {
+				continue
+			}
+
+			// Excluded pages are left untouched, their existing post is kept
+			if !s.filter.Allows(PageInfo{ID: entry.ID, Title: entry.Title}) {
+				syncedHugoPageDirs = append(syncedHugoPageDirs, pageBundleDir(hugoPageDir, entry.Title))
+				continue
+			}
+
+			pendingEntries = append(pendingEntries, entry)
+
+		case *notionapi.ChildDatabaseBlock:
+			rows, ok := s.databaseEntries(string(block.ID), block.ChildDatabase.Title, hugoPageDir, &syncedHugoPageDirs)
+			if !ok {
+				complete = false
+			}
+			pendingEntries = append(pendingEntries, rows...)
+		}
 	}
-}
 
-// downloadImage downloads an image from a URL and saves it to the specified path
-func (s *Syncer) downloadImage(imageURL, destPath string) error {
-	resp, err := http.Get(imageURL)
-	if err != nil {
-		return err
+	s.addPagesToSync(len(pendingEntries))
+	for _, entry := range pendingEntries {
+		s.syncChildPage(entry, hugoPageDir, syncTime, &syncedHugoPageDirs)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %s", resp.Status)
-	}
+	s.syncNestedPages(pageIDString, syncTime)
 
-	file, err := os.Create(destPath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+	s.reportMissingPages(hugoPageDir)
 
-	_, err = io.Copy(file, resp.Body)
-	return err
+	// Unpublished posts are removed in every mode, old directories only in full sync mode
+	oldHugoPageDirs := s.unpublishedDirs
+	if len(s.selectedPages) == 0 && complete {
+		// Clean up old directories
+		removedHugoPageDirs, _ := lo.Difference(existingHugoPageDirs, syncedHugoPageDirs)
+		oldHugoPageDirs = append(oldHugoPageDirs, removedHugoPageDirs...)
+	}
+	s.cleanupDirectories(oldHugoPageDirs, len(existingHugoPageDirs))
 }
 
-// generateImageFilename generates a unique filename for an image based on its URL
-func generateImageFilename(imageURL string) string {
-	parsedURL, err := url.Parse(imageURL)
+// databaseEntries returns the rows of a database to write as posts, next to the child pages of the
+// root page. It returns false when the rows could not be listed.
+func (s *Syncer) databaseEntries(databaseID string, databaseTitle string, hugoPageDir string, syncedHugoPageDirs *[]string) ([]pageEntry, bool) {
+	rows, err := s.fetchDatabaseRows(databaseID)
 	if err != nil {
-		// If URL parsing fails, use a hash of the full URL
-		hash := sha256.Sum256([]byte(imageURL))
-		return fmt.Sprintf("%x%s", hash[:8], filepath.Ext(imageURL))
+		s.addResult(SyncResult{
+			PageTitle:   databaseTitle,
+			Status:      "Error",
+			Path:        hugoPageDir,
+			LastUpdated: time.Now(),
+			Message:     fmt.Sprintf("failed to list the database rows: %v", err),
+		})
+		return nil, false
 	}
 
-	// Use the last part of the path as the filename
-	basename := filepath.Base(parsedURL.Path)
-	if basename == "" || basename == "." {
-		// If no filename in URL, use a hash
-		hash := sha256.Sum256([]byte(imageURL))
-		return fmt.Sprintf("%x%s", hash[:8], filepath.Ext(imageURL))
+	databaseSelected := s.isSelected(databaseID)
+	databaseExcluded := s.filter.Excludes(PageInfo{ID: databaseID, Title: databaseTitle})
+
+	var entries []pageEntry
+	for i := range rows {
+		row := &rows[i]
+		entry := pageEntry{
+			ID:             string(row.ID),
+			Title:          PageTitle(row),
+			ParentID:       databaseID,
+			CreatedTime:    row.CreatedTime,
+			LastEditedTime: row.LastEditedTime,
+			Page:           row,
+		}
+
+		if !databaseSelected && !s.isSelected(entry.ID) {
+			continue
+		}
+
+		info := PageInfo{ID: entry.ID, Title: entry.Title, ParentID: databaseID, Properties: row.Properties}
+		if databaseExcluded || !s.filter.Allows(info) {
+			*syncedHugoPageDirs = append(*syncedHugoPageDirs, pageBundleDir(hugoPageDir, entry.Title))
+			continue
+		}
+
+		entries = append(entries, entry)
 	}
 
-	return basename
+	return entries, true
 }
 
-// processImages processes all images in the markdown content
-func (s *Syncer) processImages(markdown string, postDir string, sanitizedName string) string {
-	if viper.GetBool("s3_images") {
-		return markdown // Return unchanged if using S3
+// fetchDatabaseRows returns all the rows of a database, following pagination
+func (s *Syncer) fetchDatabaseRows(databaseID string) ([]notionapi.Page, error) {
+	var rows []notionapi.Page
+	request := notionapi.DatabaseQueryRequest{
+		PageSize: 100,
 	}
 
-	baseURI := strings.TrimRight(viper.GetString("posts_base_uri"), "/")
-
-	return imageRegex.ReplaceAllStringFunc(markdown, func(match string) string {
-		submatches := imageRegex.FindStringSubmatch(match)
-		if len(submatches) != 3 {
-			return match
+	for {
+		resp, err := s.client.Database.Query(context.Background(), notionapi.DatabaseID(databaseID), &request)
+		if err != nil {
+			return nil, err
 		}
 
-		rawAlt := submatches[1]
-		imageURL := submatches[2]
+		rows = append(rows, resp.Results...)
+		if !resp.HasMore || resp.NextCursor == "" {
+			return rows, nil
+		}
+		request.StartCursor = resp.NextCursor
+	}
+}
 
-		chunks := strings.SplitN(rawAlt, "|alt:", 2)
-		caption := chunks[0]
-		alt := chunks[1]
+func (s *Syncer) syncChildPage(entry pageEntry, hugoPageDir string, syncTime time.Time, syncedHugoPageDirs *[]string) {
+	s.currentPageID = entry.ID
+	s.startPage(entry.Title)
+	defer func() {
+		s.currentPageID = ""
+		s.finishPage()
+	}()
 
-		// Generate filename and paths
-		filename := generateImageFilename(imageURL)
-		imagePath := filepath.Join(postDir, "images", filename)
-		relativeImagePath := fmt.Sprintf("%s/%s/images/%s", baseURI, sanitizedName, filename)
+	childPageId := entry.ID
+	childPageTitle := entry.Title
+	childPageLastEditedAt := entry.LastEditedTime
 
-		// Download the image
-		if err := s.downloadImage(imageURL, imagePath); err != nil {
-			// If download fails, return original markdown
-			return match
-		}
+	if strings.TrimSpace(childPageTitle) == "" {
+		s.addResult(SyncResult{
+			PageTitle:   childPageId,
+			Status:      "Skipped",
+			Path:        hugoPageDir,
+			LastUpdated: time.Now(),
+			Message:     "page has no title",
+		})
+		return
+	}
 
-		// Return Hugo shortcode
-		return fmt.Sprintf("\n\n{{< figure src=\"%s\" caption=\"%s\" alt=\"%s\" position=\"center\" captionStyle=\"font-style: italic;\" >}}\n\n",
-			relativeImagePath,
-			caption,
-			alt)
-	})
-}
+	postDir := pageBundleDir(hugoPageDir, childPageTitle)
+	sanitizedName := filepath.Base(postDir)
+	hugoPageFileName := sanitizedName + ".md"
+	hugoPageFilePath := filepath.Join(postDir, hugoPageFileName)
 
-func (s *Syncer) syncChildPage(block *notionapi.ChildPageBlock, hugoPageDir string, syncTime time.Time, syncedHugoPageDirs *[]string) {
-	childPageId := block.GetID()
-	childPageTitle := block.ChildPage.Title
-	childPageLastEditedAt := *block.GetLastEditedTime()
+	notionPage := entry.Page
+	if notionPage == nil {
+		var err error
+		notionPage, err = s.fetchPage(childPageId)
+		if err != nil {
+			// Without its properties, the page could be published by mistake
+			if viper.GetString("publish_property") != "" {
+				*syncedHugoPageDirs = append(*syncedHugoPageDirs, postDir)
+				s.addResult(SyncResult{
+					PageTitle:   childPageTitle,
+					Status:      "Error",
+					Path:        hugoPageFilePath,
+					LastUpdated: time.Now(),
+					Message:     fmt.Sprintf("failed to fetch the page properties: %v", err),
+				})
+				return
+			}
 
-	if len(s.selectedPages) > 0 && !slices.Contains(s.selectedPages, string(childPageId)) {
-		return
+			s.addResult(SyncResult{
+				PageTitle:   childPageTitle,
+				Status:      "Warning",
+				Path:        hugoPageFilePath,
+				LastUpdated: time.Now(),
+				Message:     fmt.Sprintf("failed to fetch the page properties, cover and icon: %v", err),
+			})
+		}
 	}
 
-	sanitizedName := strings.ReplaceAll(strings.ToLower(childPageTitle), " ", "_")
-	postDir := filepath.Join(hugoPageDir, sanitizedName)
-	imagesDir := filepath.Join(postDir, "images")
+	publishState := pagePublishState(notionPage)
+	if publishState == Unpublished {
+		if viper.GetString("unpublished_action") != "draft" {
+			// The post is handled here, it must not be deleted again by the cleanup of the full sync
+			*syncedHugoPageDirs = append(*syncedHugoPageDirs, postDir)
+			s.unpublishPage(postDir, hugoPageFilePath, childPageTitle)
+			return
+		}
+		publishState = Draft
+	}
 
-	if err := os.MkdirAll(postDir, 0755); err != nil {
+	if publishState == Draft && !viper.GetBool("add_front_matter") {
 		s.addResult(SyncResult{
 			PageTitle:   childPageTitle,
-			Status:      "Error",
-			Path:        postDir,
+			Status:      "Skipped",
+			Path:        hugoPageFilePath,
 			LastUpdated: time.Now(),
+			Message:     "drafts can only be written with add_front_matter",
 		})
 		return
 	}
 
-	if err := os.MkdirAll(imagesDir, 0755); err != nil {
+	if err := os.MkdirAll(postDir, 0755); err != nil {
 		s.addResult(SyncResult{
 			PageTitle:   childPageTitle,
 			Status:      "Error",
-			Path:        imagesDir,
+			Path:        postDir,
 			LastUpdated: time.Now(),
 		})
 		return
 	}
 
-	hugoPageFileName := sanitizedName + ".md"
-	hugoPageFilePath := filepath.Join(postDir, hugoPageFileName)
 	//*syncedHugoPageDirs = append(*syncedHugoPageDirs, hugoPageFilePath)
 	*syncedHugoPageDirs = append(*syncedHugoPageDirs, postDir)
 
-	markdown, err := notion2markdown.PageToMarkdown(s.client, string(childPageId))
+	markdown, err := s.cachedPageToMarkdown(childPageId, childPageLastEditedAt)
 	if err != nil {
 		s.addResult(SyncResult{
 			PageTitle:   childPageTitle,
@@ -252,18 +477,38 @@ func (s *Syncer) syncChildPage(block *notionapi.ChildPageBlock, hugoPageDir stri
 		return
 	}
 
-	// Process images in the markdown content
-	markdown = s.processImages(markdown, postDir, sanitizedName)
+	// Process images and attachments in the markdown content
+	// Nested pages are linked through the path of their parents
+	slug := sanitizedName
+	if relPath, err := filepath.Rel(s.contentDir, postDir); err == nil {
+		slug = filepath.ToSlash(relPath)
+	}
+	page := PageLocation{Dir: postDir, Slug: slug}
+	markdown, images := s.processImages(markdown, page, childPageTitle)
+	markdown = s.processAssets(markdown, page, childPageTitle)
 
 	var newContent string
-	if viper.GetBool("front_matter") {
-		hugoPageFrontMatterMap := map[string]string{
+	if viper.GetBool("add_front_matter") {
+		hugoPageFrontMatterFields := map[string]interface{}{
 			"title": childPageTitle,
 			"type":  childPageTitle,
-			"date":  childPageLastEditedAt.Format(time.RFC3339),
 		}
 
-		hugoFrontMatterYaml, err := yaml.Marshal(hugoPageFrontMatterMap)
+		if viper.GetBool("image_front_matter") && len(images) > 0 {
+			hugoPageFrontMatterFields["imagesMeta"] = images
+		}
+
+		if publishState == Draft {
+			hugoPageFrontMatterFields["draft"] = true
+		}
+
+		s.pageDates(entry, notionPage, hugoPageFilePath, childPageTitle, hugoPageFrontMatterFields)
+		if notionPage != nil {
+			s.pageCoverAndIcon(notionPage, page, childPageTitle, hugoPageFrontMatterFields)
+		}
+
+		frontMatter := buildFrontMatter(hugoPageFrontMatterFields)
+		hugoFrontMatterYaml, err := yaml.Marshal(frontMatter)
 		if err != nil {
 			s.addResult(SyncResult{
 				PageTitle:   childPageTitle,
@@ -273,6 +518,14 @@ func (s *Syncer) syncChildPage(block *notionapi.ChildPageBlock, hugoPageDir stri
 			})
 			return
 		}
+
+		if viper.GetBool("merge_front_matter") {
+			if existingContent, err := os.ReadFile(hugoPageFilePath); err == nil {
+				if merged, ok := mergeFrontMatter(existingContent, frontMatter); ok {
+					hugoFrontMatterYaml = merged
+				}
+			}
+		}
 		newContent = fmt.Sprintf("---\n%s\n---\n\n%s", hugoFrontMatterYaml, markdown)
 	} else {
 		newContent = markdown
@@ -280,6 +533,9 @@ func (s *Syncer) syncChildPage(block *notionapi.ChildPageBlock, hugoPageDir stri
 
 	if existingContent, err := os.ReadFile(hugoPageFilePath); err == nil {
 		if bytes.Equal([]byte(newContent), existingContent) {
+			s.state.recordPage(entry, hugoPageFilePath, existingContent)
+			removeConflictFile(hugoPageFilePath)
+			s.cleanupOrphans(postDir, newContent, childPageTitle)
 			s.addResult(SyncResult{
 				PageTitle:   childPageTitle,
 				Status:      "Skipped",
@@ -288,11 +544,24 @@ func (s *Syncer) syncChildPage(block *notionapi.ChildPageBlock, hugoPageDir stri
 			})
 			return
 		}
+
+		status := "Updated"
+		var message string
+		if s.state.isLocallyModified(childPageId, hugoPageFilePath, existingContent) {
+			if !s.resolveConflict(entry, hugoPageFilePath, existingContent, []byte(newContent)) {
+				return
+			}
+			status = "Conflict"
+			message = "edited locally: the local modifications were overwritten"
+		}
+
 		s.addResult(SyncResult{
 			PageTitle:   childPageTitle,
-			Status:      "Updated",
+			Status:      status,
 			Path:        hugoPageFilePath,
 			LastUpdated: syncTime,
+			Message:     message,
+			Previous:    string(existingContent),
 		})
 	} else {
 		s.addResult(SyncResult{
@@ -314,33 +583,90 @@ func (s *Syncer) syncChildPage(block *notionapi.ChildPageBlock, hugoPageDir stri
 		return
 	}
 
+	s.state.recordPage(entry, hugoPageFilePath, []byte(newContent))
+	removeConflictFile(hugoPageFilePath)
 	os.Chtimes(hugoPageFilePath, syncTime, syncTime)
+
+	// Only once the post is written, a post kept in a conflict may still use the files
+	s.cleanupOrphans(postDir, newContent, childPageTitle)
+}
+
+// unpublishPage removes the post of a page that is no longer published.
+// Posts are deleted even in selective mode, since the page was explicitly unpublished, but along
+// with the cleanup, so that max_deletions applies to them too.
+func (s *Syncer) unpublishPage(postDir string, hugoPageFilePath string, pageTitle string) {
+	if _, err := os.Stat(postDir); err != nil {
+		s.addResult(SyncResult{
+			PageTitle:   pageTitle,
+			Status:      "Skipped",
+			Path:        hugoPageFilePath,
+			LastUpdated: time.Now(),
+			Message:     "page is not published",
+		})
+		return
+	}
+
+	// Deleted with the cleanup, under the same max_deletions guard
+	s.unpublishedDirs = append(s.unpublishedDirs, postDir)
 }
 
 func (s *Syncer) addResult(result SyncResult) {
+	if result.PageID == "" {
+		result.PageID = s.currentPageID
+	}
 	s.results = append(s.results, result)
 	if s.updates != nil {
 		s.updates <- result
 	}
 }
 
+// deleteDirectories removes the posts of pages no longer synced, depending on the delete_policy
+// setting: moved to the trash (the default), marked as draft or deleted for good
 func (s *Syncer) deleteDirectories(dirPaths []string) {
+	policy := viper.GetString("delete_policy")
+
 	for _, dirPath := range dirPaths {
-		err := os.RemoveAll(dirPath)
+		var message string
+		var err error
+
+		switch policy {
+		case "delete":
+			err = os.RemoveAll(dirPath)
+		case "draft":
+			var drafted bool
+			drafted, err = markDraft(dirPath)
+			if err == nil && !drafted {
+				continue
+			}
+			message = "marked as draft"
+		default:
+			var trashPath string
+			trashPath, err = s.moveToTrash(dirPath)
+			message = fmt.Sprintf("moved to %s", trashPath)
+		}
+
 		if err != nil {
 			s.addResult(SyncResult{
 				PageTitle:   filepath.Base(dirPath),
 				Status:      "Delete Error",
 				Path:        dirPath,
 				LastUpdated: time.Now(),
+				Message:     err.Error(),
 			})
 			continue
 		}
+		// Drafted posts stay in place, their pages are kept to find them again if restored in Notion
+		if policy == "draft" {
+			s.state.refreshPages(dirPath)
+		} else {
+			s.state.forgetPages(dirPath)
+		}
 		s.addResult(SyncResult{
 			PageTitle:   filepath.Base(dirPath),
 			Status:      "Deleted",
 			Path:        dirPath,
 			LastUpdated: time.Now(),
+			Message:     message,
 		})
 	}
 }
@@ -0,0 +1,79 @@
+package sync
+
+import "testing"
+
+func TestIsSelected(t *testing.T) {
+	tests := []struct {
+		name      string
+		selected  []string
+		id        string
+		want      bool
+		wantFound string
+	}{
+		{
+			name: "full sync",
+			id:   "01234567-89ab-cdef-0123-456789abcdef",
+			want: true,
+		},
+		{
+			name:      "API ID selected by URL",
+			selected:  []string{"https://www.notion.so/Post-0123456789abcdef0123456789abcdef"},
+			id:        "01234567-89ab-cdef-0123-456789abcdef",
+			want:      true,
+			wantFound: "0123456789abcdef0123456789abcdef",
+		},
+		{
+			name:     "not selected",
+			selected: []string{"fedcba9876543210fedcba9876543210"},
+			id:       "01234567-89ab-cdef-0123-456789abcdef",
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Syncer{selectedPages: tt.selected, foundPages: make(map[string]bool)}
+
+			if got := s.isSelected(tt.id); got != tt.want {
+				t.Errorf("isSelected() = %v, want %v", got, tt.want)
+			}
+			if tt.wantFound != "" && !s.foundPages[tt.wantFound] {
+				t.Errorf("page %s not marked as found", tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestReportMissingPages(t *testing.T) {
+	const (
+		found   = "0123456789abcdef0123456789abcdef"
+		missing = "fedcba9876543210fedcba9876543210"
+	)
+
+	tests := []struct {
+		name     string
+		selected []string
+		want     []string
+	}{
+		{name: "full sync", selected: nil},
+		{name: "all found", selected: []string{found}},
+		{name: "some missing", selected: []string{found, missing}, want: []string{missing}},
+		{name: "none found", selected: []string{missing, "https://www.notion.so/Gone-00000000000000000000000000000001"}, want: []string{missing, "https://www.notion.so/Gone-00000000000000000000000000000001", "Selection"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Syncer{selectedPages: tt.selected, foundPages: map[string]bool{found: true}}
+			s.reportMissingPages("content")
+
+			if len(s.results) != len(tt.want) {
+				t.Fatalf("reportMissingPages() reported %d results, want %d: %+v", len(s.results), len(tt.want), s.results)
+			}
+			for i, result := range s.results {
+				if result.Status != "Warning" || result.PageTitle != tt.want[i] {
+					t.Errorf("result %d = %s %q, want a warning about %q", i, result.Status, result.PageTitle, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const trashDirName = "trash"
+
+// Layout of the trash folders, one per sync run
+const trashTimeLayout = "20060102-150405"
+
+// TrashRun is the posts moved to the trash by a sync run
+type TrashRun struct {
+	// Name is the time of the run, which names its folder
+	Name string
+	// Posts are the paths of the posts relative to the content directory
+	Posts []string
+}
+
+// trashPath returns where a post is moved to in the trash of the current run
+func (s *Syncer) trashPath(dirPath string) (string, error) {
+	if s.trashRun == "" {
+		run, err := createTrashRun(s.stateDir, time.Now())
+		if err != nil {
+			return "", err
+		}
+		s.trashRun = run
+	}
+
+	relPath, err := filepath.Rel(s.contentDir, dirPath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		relPath = filepath.Base(dirPath)
+	}
+
+	return filepath.Join(s.stateDir, trashDirName, s.trashRun, relPath), nil
+}
+
+// createTrashRun creates the trash folder of a sync run. Runs started in the same second get a
+// numbered suffix, so that a run never moves posts into the folder of another one.
+func createTrashRun(stateDir string, started time.Time) (string, error) {
+	trashDir := filepath.Join(stateDir, trashDirName)
+	if err := os.MkdirAll(trashDir, 0755); err != nil {
+		return "", err
+	}
+
+	name := started.Format(trashTimeLayout)
+	run := name
+	for i := 2; ; i++ {
+		err := os.Mkdir(filepath.Join(trashDir, run), 0755)
+		if err == nil {
+			return run, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return "", err
+		}
+		run = fmt.Sprintf("%s-%d", name, i)
+	}
+}
+
+// moveToTrash moves a post to the trash of the state directory, from where it can be restored
+func (s *Syncer) moveToTrash(dirPath string) (string, error) {
+	trashPath, err := s.trashPath(dirPath)
+	if err != nil {
+		return "", err
+	}
+	if err := moveDir(dirPath, trashPath); err != nil {
+		return "", err
+	}
+
+	return trashPath, nil
+}
+
+// moveDir renames a directory, or copies it when it is moved to another file system
+func moveDir(src string, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return fmt.Errorf("%s already exists", dst)
+	}
+
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, relPath)
+
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		return linkOrCopy(path, target)
+	})
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(src)
+}
+
+// postPath returns the path of the post of a page bundle, named after it
+func postPath(dirPath string) string {
+	return filepath.Join(dirPath, filepath.Base(dirPath)+".md")
+}
+
+// isDraft reports whether the post of a page bundle is a draft
+func isDraft(dirPath string) bool {
+	content, err := os.ReadFile(postPath(dirPath))
+	if err != nil {
+		return false
+	}
+
+	frontMatter, _, ok := parseFrontMatter(content)
+	draft, _ := frontMatter["draft"].(bool)
+	return ok && draft
+}
+
+// markDraft sets draft in the front matter of a post, which Hugo no longer publishes. It returns
+// false when the post already is a draft.
+func markDraft(dirPath string) (bool, error) {
+	if isDraft(dirPath) {
+		return false, nil
+	}
+
+	content, err := os.ReadFile(postPath(dirPath))
+	if err != nil {
+		return false, err
+	}
+
+	frontMatter, body, ok := parseFrontMatter(content)
+	if !ok {
+		frontMatter = make(map[string]interface{})
+		body = append([]byte("\n"), content...)
+	}
+	frontMatter["draft"] = true
+
+	frontMatterYaml, err := yaml.Marshal(frontMatter)
+	if err != nil {
+		return false, err
+	}
+
+	return true, os.WriteFile(postPath(dirPath), []byte(fmt.Sprintf("---\n%s\n---\n%s", frontMatterYaml, body)), 0644)
+}
+
+// LoadTrash lists the sync runs of the trash of stateDir, oldest first, with the posts they deleted
+func LoadTrash(stateDir string) ([]TrashRun, error) {
+	trashDir := filepath.Join(stateDir, trashDirName)
+	entries, err := os.ReadDir(trashDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []TrashRun
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		posts, err := trashedPosts(filepath.Join(trashDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, TrashRun{Name: entry.Name(), Posts: posts})
+	}
+	slices.SortFunc(runs, func(a, b TrashRun) int { return compareTrashRuns(a.Name, b.Name) })
+
+	return runs, nil
+}
+
+// compareTrashRuns orders trash runs by start time, then by their numbered suffix, so that the
+// tenth run of a second comes after the second one
+func compareTrashRuns(a string, b string) int {
+	nameA, suffixA := splitTrashRun(a)
+	nameB, suffixB := splitTrashRun(b)
+	if c := strings.Compare(nameA, nameB); c != 0 {
+		return c
+	}
+	return suffixA - suffixB
+}
+
+// splitTrashRun splits the name of a trash run into its start time and numbered suffix
+func splitTrashRun(run string) (string, int) {
+	if len(run) > len(trashTimeLayout)+1 && run[len(trashTimeLayout)] == '-' {
+		if suffix, err := strconv.Atoi(run[len(trashTimeLayout)+1:]); err == nil {
+			return run[:len(trashTimeLayout)], suffix
+		}
+	}
+	return run, 1
+}
+
+// PruneTrash removes the oldest runs of the trash of stateDir, keeping the given number of them.
+// Nothing is removed when keep is 0. It returns the names of the removed runs.
+func PruneTrash(stateDir string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+
+	runs, err := LoadTrash(stateDir)
+	if err != nil || len(runs) <= keep {
+		return nil, err
+	}
+
+	var pruned []string
+	for _, run := range runs[:len(runs)-keep] {
+		if err := os.RemoveAll(filepath.Join(stateDir, trashDirName, run.Name)); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, run.Name)
+	}
+
+	return pruned, nil
+}
+
+// trashedPosts finds the post bundles of a trash run, the folders holding a post named after them.
+// The sub-pages of a post are restored along with it.
+func trashedPosts(runDir string) ([]string, error) {
+	var posts []string
+	err := filepath.WalkDir(runDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || path == runDir {
+			return err
+		}
+
+		if _, err := os.Stat(postPath(path)); err != nil {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(runDir, path)
+		if err != nil {
+			return err
+		}
+		posts = append(posts, relPath)
+
+		return filepath.SkipDir
+	})
+
+	return posts, err
+}
+
+// Restore moves posts deleted by a sync run back to the content directory. Without posts, all the
+// posts of the run are restored. Posts whose path is used again are left in the trash.
+func Restore(stateDir string, contentDir string, run string, posts []string) ([]SyncResult, error) {
+	runDir := filepath.Join(stateDir, trashDirName, run)
+	trashed, err := trashedPosts(runDir)
+	if err != nil {
+		return nil, fmt.Errorf("no trash run named %q: %v", run, err)
+	}
+
+	if len(posts) == 0 {
+		posts = trashed
+	}
+
+	var results []SyncResult
+	for _, post := range posts {
+		post = filepath.Clean(post)
+		if !slices.Contains(trashed, post) {
+			return results, fmt.Errorf("no post %q in trash run %s", post, run)
+		}
+
+		restoredPath := filepath.Join(contentDir, post)
+		result := SyncResult{
+			PageTitle:   filepath.Base(post),
+			Status:      "Restored",
+			Path:        restoredPath,
+			LastUpdated: time.Now(),
+		}
+		if err := moveDir(filepath.Join(runDir, post), restoredPath); err != nil {
+			result.Status = "Error"
+			result.Message = err.Error()
+		} else {
+			removeEmptyDirs(filepath.Dir(filepath.Join(runDir, post)), filepath.Dir(runDir))
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// containsPath reports whether path is dir or one of its descendants
+func containsPath(dir string, path string) bool {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	relPath, err := filepath.Rel(absDir, absPath)
+	return err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
+// removeEmptyDirs removes dir and its parents up to root, as long as they are empty
+func removeEmptyDirs(dir string, root string) {
+	for dir != root && strings.HasPrefix(dir, root) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
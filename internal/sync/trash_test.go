@@ -0,0 +1,147 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestCreateTrashRun(t *testing.T) {
+	stateDir := t.TempDir()
+	started := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	// Runs started in the same second must not share their folder
+	want := []string{"20240301-123000", "20240301-123000-2", "20240301-123000-3"}
+	for _, name := range want {
+		run, err := createTrashRun(stateDir, started)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run != name {
+			t.Errorf("createTrashRun() = %q, want %q", run, name)
+		}
+	}
+}
+
+func TestContainsPath(t *testing.T) {
+	tests := []struct {
+		dir  string
+		path string
+		want bool
+	}{
+		{dir: "content", path: "content", want: true},
+		{dir: "content", path: "content/.hugo-notion", want: true},
+		{dir: "content/posts", path: "content/posts/state/trash", want: true},
+		{dir: "content/posts", path: "content", want: false},
+		{dir: "content/posts", path: "content/posts-old", want: false},
+		{dir: "content/..state", path: "content/..state/trash", want: true},
+		{dir: "content", path: ".hugo-notion", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dir+" "+tt.path, func(t *testing.T) {
+			if got := containsPath(filepath.FromSlash(tt.dir), filepath.FromSlash(tt.path)); got != tt.want {
+				t.Errorf("containsPath(%q, %q) = %t, want %t", tt.dir, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPruneTrash(t *testing.T) {
+	runs := []string{"20240301-123000", "20240301-123000-2", "20240301-123000-10", "20240302-080000"}
+
+	tests := []struct {
+		name       string
+		keep       int
+		wantPruned []string
+		wantKept   []string
+	}{
+		{name: "keep all", keep: 0, wantKept: runs},
+		{name: "below the retention", keep: 5, wantKept: runs},
+		{name: "numbered runs in order", keep: 2, wantPruned: runs[:2], wantKept: runs[2:]},
+		{name: "last run only", keep: 1, wantPruned: runs[:3], wantKept: runs[3:]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stateDir := t.TempDir()
+			for _, run := range runs {
+				if err := os.MkdirAll(filepath.Join(stateDir, trashDirName, run, "post"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			pruned, err := PruneTrash(stateDir, tt.keep)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(pruned, tt.wantPruned) {
+				t.Errorf("PruneTrash() = %v, want %v", pruned, tt.wantPruned)
+			}
+
+			trash, err := LoadTrash(stateDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var kept []string
+			for _, run := range trash {
+				kept = append(kept, run.Name)
+			}
+			if !reflect.DeepEqual(kept, tt.wantKept) {
+				t.Errorf("trash runs = %v, want %v", kept, tt.wantKept)
+			}
+		})
+	}
+}
+
+func TestDeleteDirectoriesState(t *testing.T) {
+	tests := []struct {
+		policy   string
+		wantKept bool
+	}{
+		{policy: "trash", wantKept: false},
+		{policy: "delete", wantKept: false},
+		{policy: "draft", wantKept: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.Set("delete_policy", tt.policy)
+
+			contentDir := t.TempDir()
+			dirPath := filepath.Join(contentDir, "my-post")
+			if err := os.Mkdir(dirPath, 0755); err != nil {
+				t.Fatal(err)
+			}
+			content := []byte("---\ntitle: My post\n---\nBody\n")
+			if err := os.WriteFile(postPath(dirPath), content, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			state := &State{Pages: map[string]*PageState{
+				"page": {Path: postPath(dirPath), Hash: postHash(content)},
+			}}
+			s := &Syncer{contentDir: contentDir, stateDir: t.TempDir(), state: state}
+			s.deleteDirectories([]string{dirPath})
+
+			if len(s.results) != 1 || s.results[0].Status != "Deleted" {
+				t.Fatalf("deleteDirectories() results = %+v, want a single Deleted result", s.results)
+			}
+			if _, ok := state.Pages["page"]; ok != tt.wantKept {
+				t.Fatalf("page kept in the state = %t, want %t", ok, tt.wantKept)
+			}
+			// A drafted post is not a local modification of its page
+			if tt.wantKept {
+				if status := state.PageStatus("page", time.Time{}); status != PageUnchanged {
+					t.Errorf("PageStatus() = %q, want %q", status, PageUnchanged)
+				}
+			}
+		})
+	}
+}
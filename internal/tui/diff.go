@@ -0,0 +1,167 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Lines of unchanged content shown around each change
+const diffContext = 3
+
+// Posts longer than this are not diffed line by line
+const maxDiffLines = 5000
+
+// Posts needing more line insertions and deletions are not diffed, the memory of the comparison
+// growing with their square
+const maxDiffEdits = 1000
+
+var (
+	diffAddedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	diffRemovedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	diffHunkStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("6"))
+)
+
+// diffLine is a line of a diff, prefixed with ' ', '+' or '-'
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// lineDiff compares two texts line by line with the Myers algorithm, which runs in O(ND) for N
+// lines and D edits. It returns false when more than maxEdits lines were inserted or deleted.
+func lineDiff(before string, after string, maxEdits int) ([]diffLine, bool) {
+	a := strings.Split(before, "\n")
+	b := strings.Split(after, "\n")
+
+	// v[offset+k] is the furthest line of a reached on the diagonal k (x - y), and trace keeps the
+	// diagonals [-d, d] of each step d to walk the edit path back
+	offset := maxEdits + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+
+	edits := -1
+	for d := 0; d <= maxEdits && edits < 0; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // Insertion, down from the diagonal k+1
+			} else {
+				x = v[offset+k-1] + 1 // Deletion, right from the diagonal k-1
+			}
+
+			y := x - k
+			for x < len(a) && y < len(b) && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= len(a) && y >= len(b) {
+				edits = d
+				break
+			}
+		}
+		trace = append(trace, slices.Clone(v[offset-d:offset+d+1]))
+	}
+
+	if edits < 0 {
+		return nil, false
+	}
+
+	var lines []diffLine
+	x, y := len(a), len(b)
+	for d := edits; d > 0; d-- {
+		previous := trace[d-1]
+		k := x - y
+
+		var previousK int
+		if k == -d || (k != d && previous[k-1+d-1] < previous[k+1+d-1]) {
+			previousK = k + 1
+		} else {
+			previousK = k - 1
+		}
+		previousX := previous[previousK+d-1]
+		previousY := previousX - previousK
+
+		for x > previousX && y > previousY {
+			lines = append(lines, diffLine{' ', a[x-1]})
+			x--
+			y--
+		}
+		if x == previousX {
+			lines = append(lines, diffLine{'+', b[y-1]})
+			y--
+		} else {
+			lines = append(lines, diffLine{'-', a[x-1]})
+			x--
+		}
+	}
+	for ; x > 0; x-- {
+		lines = append(lines, diffLine{' ', a[x-1]})
+	}
+
+	slices.Reverse(lines)
+	return lines, true
+}
+
+// renderDiff shows the changed lines of a post with some context, in the style of a unified diff
+func renderDiff(before string, after string) string {
+	if strings.Count(before, "\n") > maxDiffLines || strings.Count(after, "\n") > maxDiffLines {
+		return "The post is too long to be compared."
+	}
+
+	lines, ok := lineDiff(before, after, maxDiffEdits)
+	if !ok {
+		return "The post changed too much to be compared."
+	}
+
+	// Keep the lines close enough to a change
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		if line.op == ' ' {
+			continue
+		}
+		for k := max(0, i-diffContext); k <= min(len(lines)-1, i+diffContext); k++ {
+			keep[k] = true
+		}
+	}
+
+	var out strings.Builder
+	beforeLine, afterLine := 1, 1
+	inHunk := false
+	for i, line := range lines {
+		if keep[i] {
+			if !inHunk {
+				out.WriteString(diffHunkStyle.Render(fmt.Sprintf("@@ -%d +%d @@", beforeLine, afterLine)) + "\n")
+				inHunk = true
+			}
+
+			switch line.op {
+			case '+':
+				out.WriteString(diffAddedStyle.Render("+"+line.text) + "\n")
+			case '-':
+				out.WriteString(diffRemovedStyle.Render("-"+line.text) + "\n")
+			default:
+				out.WriteString(" " + line.text + "\n")
+			}
+		} else {
+			inHunk = false
+		}
+
+		if line.op != '+' {
+			beforeLine++
+		}
+		if line.op != '-' {
+			afterLine++
+		}
+	}
+
+	if out.Len() == 0 {
+		return "No changes."
+	}
+
+	return out.String()
+}
@@ -0,0 +1,100 @@
+package tui
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// lcsLength is the length of the longest common subsequence of two lists of lines
+func lcsLength(a []string, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	return lcs[0][0]
+}
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+	}{
+		{name: "identical", before: "a\nb\nc", after: "a\nb\nc"},
+		{name: "empty before", before: "", after: "a\nb"},
+		{name: "empty after", before: "a\nb", after: ""},
+		{name: "line changed", before: "a\nb\nc", after: "a\nx\nc"},
+		{name: "lines moved", before: "a\nb\nc\nd", after: "c\nd\na\nb"},
+		{name: "unrelated", before: "a\nb\nc", after: "x\ny"},
+	}
+
+	// Random texts over a few distinct lines, so that they share many of them
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		var before, after []string
+		for j := random.Intn(30); j > 0; j-- {
+			before = append(before, string(rune('a'+random.Intn(4))))
+		}
+		for j := random.Intn(30); j > 0; j-- {
+			after = append(after, string(rune('a'+random.Intn(4))))
+		}
+		tests = append(tests, struct {
+			name   string
+			before string
+			after  string
+		}{name: "random", before: strings.Join(before, "\n"), after: strings.Join(after, "\n")})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, ok := lineDiff(tt.before, tt.after, maxDiffEdits)
+			if !ok {
+				t.Fatal("lineDiff() gave up")
+			}
+
+			var before, after []string
+			common := 0
+			for _, line := range lines {
+				if line.op != '+' {
+					before = append(before, line.text)
+				}
+				if line.op != '-' {
+					after = append(after, line.text)
+				}
+				if line.op == ' ' {
+					common++
+				}
+			}
+
+			if got := strings.Join(before, "\n"); got != tt.before {
+				t.Errorf("diff does not rebuild the old text: %q", got)
+			}
+			if got := strings.Join(after, "\n"); got != tt.after {
+				t.Errorf("diff does not rebuild the new text: %q", got)
+			}
+			if want := lcsLength(strings.Split(tt.before, "\n"), strings.Split(tt.after, "\n")); common != want {
+				t.Errorf("diff keeps %d common lines, want %d", common, want)
+			}
+		})
+	}
+}
+
+func TestLineDiffTooManyEdits(t *testing.T) {
+	if _, ok := lineDiff("a\nb\nc", "x\ny\nz", 5); ok {
+		t.Error("lineDiff() compared texts needing 6 edits with a maximum of 5")
+	}
+	if _, ok := lineDiff("a\nb\nc", "x\ny\nz", 6); !ok {
+		t.Error("lineDiff() gave up on texts needing 6 edits with a maximum of 6")
+	}
+}
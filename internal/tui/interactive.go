@@ -2,42 +2,43 @@ package tui
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/jomei/notionapi"
+	"github.com/ma111e/hugo-notion/internal/sync"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
 )
 
-// KeyMap defines all keybindings
-type KeyMap struct {
-	Toggle key.Binding
-	Quit   key.Binding
-	Start  key.Binding
-}
-
-var DefaultKeyMap = KeyMap{
-	Toggle: key.NewBinding(
-		key.WithKeys(" "),
-		key.WithHelp("space", "toggle selection"),
-	),
-	Quit: key.NewBinding(
-		key.WithKeys("q", "esc"),
-		key.WithHelp("q/esc", "quit"),
-	),
-	Start: key.NewBinding(
-		key.WithKeys("enter"),
-		key.WithHelp("enter", "start sync"),
-	),
-}
-
-// Item represents a Notion page/database in the selection list
+// Item represents a Notion page/database in the selection tree
 type Item struct {
 	title    string
 	id       string
 	itemType string
 	selected bool
+
+	lastEditedTime time.Time
+	status         sync.PageStatus
+
+	parentID string
+	depth    int
+	// leaf items are known to have no sub-pages
+	leaf     bool
+	loaded   bool
+	loading  bool
+	expanded bool
+	// subtree items select their children once they are loaded
+	subtree bool
 }
 
 func (i Item) Title() string {
@@ -45,29 +46,99 @@ func (i Item) Title() string {
 	if i.selected {
 		checkbox = "[✓]"
 	}
-	return checkbox + " " + i.title
+
+	marker := "▸"
+	switch {
+	case i.expanded:
+		marker = "▾"
+	case i.leaf:
+		marker = " "
+	}
+
+	return strings.Repeat("  ", i.depth) + marker + " " + checkbox + " " + i.title
 }
 
 func (i Item) Description() string {
-	return i.itemType
+	description := i.itemType
+	if i.status != "" {
+		description += " • " + statusBadgeStyles[i.status].Render(string(i.status))
+	}
+	if i.loading {
+		description += " • loading..."
+	}
+
+	return strings.Repeat("  ", i.depth) + "  " + description
 }
 
 func (i Item) FilterValue() string {
 	return i.title
 }
 
+// Orders of the items of the tree
+type sortMode int
+
+const (
+	// sortNotion keeps the order of Notion
+	sortNotion sortMode = iota
+	sortTitle
+	// sortEdited lists the last edited pages first
+	sortEdited
+)
+
+var sortModeNames = map[sortMode]string{
+	sortNotion: "",
+	sortTitle:  "title",
+	sortEdited: "edit date",
+}
+
+// Colors of the change status of pages, relative to their post
+var statusBadgeStyles = map[sync.PageStatus]lipgloss.Style{
+	sync.PageNew:             lipgloss.NewStyle().Foreground(lipgloss.Color("2")),   // Green
+	sync.PageChanged:         lipgloss.NewStyle().Foreground(lipgloss.Color("6")),   // Cyan
+	sync.PageUnchanged:       lipgloss.NewStyle().Foreground(lipgloss.Color("240")), // Gray
+	sync.PageLocalModified:   lipgloss.NewStyle().Foreground(lipgloss.Color("208")), // Orange
+	sync.PageDeletedUpstream: lipgloss.NewStyle().Foreground(lipgloss.Color("1")),   // Red
+}
+
+// childrenMsg carries the children of an item, or of the root page, once fetched
+type childrenMsg struct {
+	parentID string
+	items    []Item
+	err      error
+}
+
 // SelectionModel represents the selection screen
 type SelectionModel struct {
-	list     list.Model
-	items    []Item
-	selected map[string]bool
+	list list.Model
+	// nodes holds every item loaded in the tree, and children their IDs by parent ID
+	nodes    map[string]*Item
+	children map[string][]string
 	client   *notionapi.Client
 	pageID   string
 	done     bool
+	aborted  bool
 	err      error
 	keymap   KeyMap
+	help     help.Model
+	sort     sortMode
 	spinner  spinner.Model
 	loading  bool
+	// preselected pages, from a saved selection, are checked once they are loaded. Those never loaded,
+	// under collapsed pages or no longer in Notion, are synced anyway: the sync warns about the
+	// ones it does not find.
+	preselected map[string]bool
+
+	width          int
+	height         int
+	preview        viewport.Model
+	showPreview    bool
+	previewID      string
+	previews       map[string]string
+	markdownCache  *sync.MarkdownCache
+	darkBackground bool
+
+	// state tells what the last sync wrote, to compare pages with their post
+	state *sync.State
 }
 
 func NewSelectionModel(client *notionapi.Client, pageID string) SelectionModel {
@@ -79,35 +150,88 @@ func NewSelectionModel(client *notionapi.Client, pageID string) SelectionModel {
 	items := []list.Item{}
 	l := list.New(items, delegate, 0, 0)
 	l.Title = "Select Pages to Sync"
-	l.SetShowHelp(true)
+	l.SetShowHelp(false)
 	l.SetFilteringEnabled(true)
+	l.KeyMap = selectionListKeyMap(DefaultKeyMap)
+
+	// Without a state, every page shows as new
+	state, _ := sync.LoadState(viper.GetString("state_dir"))
 
 	return SelectionModel{
-		list:     l,
-		items:    []Item{},
-		selected: make(map[string]bool),
-		client:   client,
-		pageID:   pageID,
-		keymap:   DefaultKeyMap,
-		spinner:  s,
-		loading:  true,
+		list:        l,
+		nodes:       make(map[string]*Item),
+		children:    make(map[string][]string),
+		client:      client,
+		pageID:      pageID,
+		keymap:      DefaultKeyMap,
+		help:        help.New(),
+		spinner:     s,
+		loading:     true,
+		preselected: make(map[string]bool),
+
+		preview:        viewport.New(0, 0),
+		showPreview:    true,
+		previews:       make(map[string]string),
+		markdownCache:  sync.NewMarkdownCache(),
+		darkBackground: lipgloss.HasDarkBackground(),
+
+		state: state,
+	}
+}
+
+// NewSelectionModelWithSelection creates a selection screen with the given pages already checked
+func NewSelectionModelWithSelection(client *notionapi.Client, pageID string, preselected []string) SelectionModel {
+	m := NewSelectionModel(client, pageID)
+	for _, id := range preselected {
+		m.preselected[sync.NormalizePageID(id)] = true
 	}
+	return m
+}
+
+// SetKeyMap replaces the keybindings, usually with those of LoadKeyMap
+func (m *SelectionModel) SetKeyMap(keymap KeyMap) {
+	m.keymap = keymap
+	m.list.KeyMap = selectionListKeyMap(keymap)
+}
+
+// selectionListKeyMap returns the bindings of the list of the selection screen, left with the keys
+// the keymap does not use
+func selectionListKeyMap(keymap KeyMap) list.KeyMap {
+	listKeys := keymap.listKeyMap(list.DefaultKeyMap())
+	// Quitting and help are handled by the selection screen
+	listKeys.Quit.SetEnabled(false)
+	listKeys.ForceQuit.SetEnabled(false)
+	listKeys.ShowFullHelp.SetEnabled(false)
+	listKeys.CloseFullHelp.SetEnabled(false)
+
+	return listKeys
 }
 
 func (m SelectionModel) Init() tea.Cmd {
 	return tea.Batch(
 		m.spinner.Tick,
-		m.fetchPages,
+		m.fetchChildren(Item{id: m.pageID, itemType: "page"}),
 	)
 }
 
 func (m SelectionModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m, cmd := m.update(msg)
+	if m.done {
+		return m, cmd
+	}
+
+	// The preview follows the highlighted item, whatever moved it
+	return m, tea.Batch(cmd, m.schedulePreview())
+}
+
+func (m SelectionModel) update(msg tea.Msg) (SelectionModel, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
-		m.list.SetWidth(msg.Width)
-		m.list.SetHeight(msg.Height - 4)
+		m.width = msg.Width
+		m.height = msg.Height
+		m.resize()
 		return m, nil
 
 	case spinner.TickMsg:
@@ -119,45 +243,157 @@ func (m SelectionModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.KeyMsg:
+		// ctrl+c always aborts, even when it is not bound to Quit
+		if msg.String() == "ctrl+c" {
+			m.done = true
+			m.aborted = true
+			return m, tea.Quit
+		}
+
 		if m.loading {
 			return m, nil // Ignore keyboard input while loading
 		}
 
+		// Keys are typed into the filter while it is being edited
+		if m.list.FilterState() == list.Filtering {
+			break
+		}
+
 		switch {
 		case key.Matches(msg, m.keymap.Quit):
 			m.done = true
+			m.aborted = true
 			return m, tea.Quit
 
 		case key.Matches(msg, m.keymap.Start):
-			if len(m.selected) > 0 {
+			if len(m.GetSelectedPages()) > 0 {
 				m.done = true
 				return m, tea.Quit
 			}
 			return m, nil
 
 		case key.Matches(msg, m.keymap.Toggle):
-			if len(m.list.Items()) > 0 {
-				i := m.list.SelectedItem().(Item)
-				i.selected = !i.selected
-				m.selected[i.id] = i.selected
-				m.updateListItem(i)
+			if node := m.currentNode(); node != nil {
+				m.setSelected(node, !node.selected)
+				m.refreshList()
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keymap.Subtree):
+			if node := m.currentNode(); node != nil {
+				cmd = m.selectSubtree(node, !node.selected)
+				m.refreshList()
+			}
+			return m, cmd
+
+		case key.Matches(msg, m.keymap.Expand):
+			if node := m.currentNode(); node != nil && !node.leaf && !node.expanded {
+				node.expanded = true
+				if !node.loaded && !node.loading {
+					node.loading = true
+					cmd = m.fetchChildren(*node)
+				}
+				m.refreshList()
+			}
+			return m, cmd
+
+		case key.Matches(msg, m.keymap.All):
+			for _, node := range m.nodes {
+				m.setSelected(node, true)
+			}
+			m.refreshList()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.None):
+			for _, node := range m.nodes {
+				m.setSelected(node, false)
+			}
+			clear(m.preselected)
+			m.refreshList()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.Invert):
+			for _, node := range m.nodes {
+				m.setSelected(node, !node.selected)
+			}
+			m.refreshList()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.Filtered):
+			if m.list.FilterState() != list.FilterApplied {
+				return m, m.list.NewStatusMessage("No filter applied")
+			}
+			for _, listItem := range m.list.VisibleItems() {
+				m.setSelected(m.nodes[listItem.(Item).id], true)
+			}
+			m.refreshList()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.Sort):
+			m.sort = (m.sort + 1) % sortMode(len(sortModeNames))
+			m.refreshList()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.Help):
+			m.help.ShowAll = !m.help.ShowAll
+			m.resize()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.Changed):
+			for _, node := range m.nodes {
+				if node.status == sync.PageNew || node.status == sync.PageChanged {
+					m.setSelected(node, true)
+				}
+			}
+			m.refreshList()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.Preview):
+			m.showPreview = !m.showPreview
+			m.previewID = ""
+			m.resize()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.Down):
+			m.preview.HalfViewDown()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.Up):
+			m.preview.HalfViewUp()
+			return m, nil
+
+		case key.Matches(msg, m.keymap.Collapse):
+			if node := m.currentNode(); node != nil {
+				if node.expanded {
+					node.expanded = false
+				} else if parent, ok := m.nodes[node.parentID]; ok {
+					m.selectNode(parent.id)
+					return m, nil
+				}
+				m.refreshList()
 			}
 			return m, nil
 		}
 
-	case []Item:
-		m.loading = false
-		listItems := make([]list.Item, len(msg))
-		for i, item := range msg {
-			listItems[i] = item
+	case childrenMsg:
+		cmd = m.addChildren(msg)
+		return m, cmd
+
+	case previewTickMsg:
+		if msg.id == m.previewID {
+			return m, m.fetchPreview(msg.id)
 		}
-		m.items = msg
-		m.list.SetItems(listItems)
 		return m, nil
 
-	case error:
-		m.loading = false
-		m.err = msg
+	case previewMsg:
+		if msg.err != nil {
+			m.previews[msg.id] = fmt.Sprintf("Failed to load the preview: %v", msg.err)
+		} else {
+			m.previews[msg.id] = msg.markdown
+		}
+		if msg.id == m.previewID {
+			m.renderPreview()
+		}
 		return m, nil
 	}
 
@@ -183,59 +419,322 @@ func (m SelectionModel) View() string {
 			Render("Error: " + m.err.Error())
 	}
 
+	view := m.list.View()
+	if m.showPreview {
+		view = lipgloss.NewStyle().Width(m.list.Width()).Render(view)
+		view = lipgloss.JoinHorizontal(lipgloss.Top, view, previewStyle.Render(m.preview.View()))
+	}
+
 	return lipgloss.JoinVertical(lipgloss.Left,
-		m.list.View(),
-		"\nspace: toggle selection • enter: start sync • q/esc: quit",
+		view,
+		"",
+		m.helpView(),
 	)
 }
 
-func (m *SelectionModel) updateListItem(item Item) {
+// helpView lists the keybindings, generated from the keymap
+func (m SelectionModel) helpView() string {
+	return m.help.View(selectionHelp{keymap: m.keymap, list: m.list.KeyMap})
+}
+
+// currentNode returns the tree node of the highlighted item
+func (m SelectionModel) currentNode() *Item {
+	item, ok := m.list.SelectedItem().(Item)
+	if !ok {
+		return nil
+	}
+
+	return m.nodes[item.id]
+}
+
+// selectNode highlights the item with the given ID, if it is visible
+func (m *SelectionModel) selectNode(id string) {
 	for i, listItem := range m.list.Items() {
-		if listItem.(Item).id == item.id {
-			m.list.SetItem(i, item)
-			break
+		if listItem.(Item).id == id {
+			m.list.Select(i)
+			return
 		}
 	}
 }
 
-func (m SelectionModel) fetchPages() tea.Msg {
-	pagination := notionapi.Pagination{PageSize: 100}
-	resp, err := m.client.Block.GetChildren(context.Background(), notionapi.BlockID(m.pageID), &pagination)
-	if err != nil {
-		return err
+func (m *SelectionModel) setSelected(node *Item, selected bool) {
+	// Pages deleted from Notion have nothing left to sync
+	if node.status == sync.PageDeletedUpstream {
+		return
 	}
 
-	items := make([]Item, 0)
-	for _, block := range resp.Results {
-		switch b := block.(type) {
-		case *notionapi.ChildPageBlock:
+	node.selected = selected
+	delete(m.preselected, sync.NormalizePageID(node.id))
+}
+
+// selectSubtree (de)selects an item and all its descendants. Sub-pages that are not loaded yet
+// are fetched, and selected as they arrive.
+func (m *SelectionModel) selectSubtree(node *Item, selected bool) tea.Cmd {
+	m.setSelected(node, selected)
+	node.subtree = selected
+
+	if !node.loaded {
+		// Databases are synced with all their rows, so they are only listed when expanded, as are
+		// the sub-pages of rows
+		if !selected || node.leaf || node.loading || node.itemType == "database" || m.isRow(node) {
+			return nil
+		}
+		node.loading = true
+		return m.fetchChildren(*node)
+	}
+
+	var cmds []tea.Cmd
+	for _, childID := range m.children[node.id] {
+		cmds = append(cmds, m.selectSubtree(m.nodes[childID], selected))
+	}
+
+	return tea.Batch(cmds...)
+}
+
+// isRow reports whether an item is a row of a database
+func (m SelectionModel) isRow(node *Item) bool {
+	parent, ok := m.nodes[node.parentID]
+	return ok && parent.itemType == "database"
+}
+
+// addChildren inserts fetched items under their parent
+func (m *SelectionModel) addChildren(msg childrenMsg) tea.Cmd {
+	parent, isNode := m.nodes[msg.parentID]
+
+	if !isNode {
+		m.loading = false
+		if msg.err != nil {
+			m.err = msg.err
+			return nil
+		}
+	} else {
+		parent.loading = false
+		if msg.err != nil {
+			parent.expanded = false
+			m.refreshList()
+			return m.list.NewStatusMessage(fmt.Sprintf("Failed to load %s: %v", parent.title, msg.err))
+		}
+		parent.loaded = true
+		parent.leaf = len(msg.items) == 0
+	}
+
+	var cmds []tea.Cmd
+	ids := make([]string, 0, len(msg.items))
+	for _, item := range msg.items {
+		node := item
+		node.parentID = msg.parentID
+		if isNode {
+			node.depth = parent.depth + 1
+		}
+		if m.preselected[sync.NormalizePageID(node.id)] {
+			node.selected = true
+			delete(m.preselected, sync.NormalizePageID(node.id))
+		}
+
+		m.nodes[node.id] = &node
+		ids = append(ids, node.id)
+
+		if isNode && parent.subtree {
+			cmds = append(cmds, m.selectSubtree(&node, true))
+		}
+	}
+	m.children[msg.parentID] = ids
+
+	m.refreshList()
+	return tea.Batch(cmds...)
+}
+
+// sortedChildren returns the IDs of the children of an item in the current order
+func (m SelectionModel) sortedChildren(parentID string) []string {
+	ids := slices.Clone(m.children[parentID])
+
+	switch m.sort {
+	case sortTitle:
+		slices.SortStableFunc(ids, func(a, b string) int {
+			return strings.Compare(strings.ToLower(m.nodes[a].title), strings.ToLower(m.nodes[b].title))
+		})
+	case sortEdited:
+		slices.SortStableFunc(ids, func(a, b string) int {
+			return m.nodes[b].lastEditedTime.Compare(m.nodes[a].lastEditedTime)
+		})
+	}
+
+	return ids
+}
+
+// refreshList lists the items of the tree whose parents are all expanded
+func (m *SelectionModel) refreshList() {
+	var items []list.Item
+
+	var walk func(parentID string)
+	walk = func(parentID string) {
+		for _, id := range m.sortedChildren(parentID) {
+			node := m.nodes[id]
+			items = append(items, *node)
+			if node.expanded {
+				walk(id)
+			}
+		}
+	}
+	walk(m.pageID)
+
+	m.list.Title = "Select Pages to Sync"
+	if m.sort != sortNotion {
+		m.list.Title += " by " + sortModeNames[m.sort]
+	}
+	if len(m.preselected) > 0 {
+		m.list.Title += fmt.Sprintf(" (%d more selected, not loaded)", len(m.preselected))
+	}
+
+	m.list.SetItems(items)
+}
+
+// fetchChildren lists the sub-pages and databases of a page, or the rows of a database, along with
+// their change status. Pages excluded by the configuration are not offered, and pages synced before
+// but no longer found are listed as deleted upstream.
+func (m SelectionModel) fetchChildren(parent Item) tea.Cmd {
+	client := m.client
+	state := m.state
+
+	return func() tea.Msg {
+		filter, err := sync.LoadPageFilter()
+		if err != nil {
+			return childrenMsg{parentID: parent.id, err: err}
+		}
+
+		var items []Item
+		var ids []string
+		if parent.itemType == "database" {
+			items, ids, err = fetchDatabaseItems(client, filter, parent.id)
+		} else {
+			items, ids, err = fetchPageItems(client, filter, parent.id)
+		}
+		if err != nil {
+			return childrenMsg{parentID: parent.id, err: err}
+		}
+
+		for i := range items {
+			if items[i].itemType == "page" {
+				items[i].status = state.PageStatus(items[i].id, items[i].lastEditedTime)
+			}
+		}
+
+		for _, id := range state.DeletedPages(parent.id, ids) {
 			items = append(items, Item{
-				title:    b.ChildPage.Title,
-				id:       string(b.ID),
+				title:    state.Pages[id].Title,
+				id:       id,
 				itemType: "page",
+				leaf:     true,
+				status:   sync.PageDeletedUpstream,
 			})
-		case *notionapi.ChildDatabaseBlock:
+		}
+
+		return childrenMsg{parentID: parent.id, items: items}
+	}
+}
+
+// fetchPageItems lists the child pages and databases of a page, and returns the IDs of all of them,
+// excluded or not
+func fetchPageItems(client *notionapi.Client, filter *sync.PageFilter, pageID string) ([]Item, []string, error) {
+	items := make([]Item, 0)
+	var ids []string
+	pagination := notionapi.Pagination{PageSize: 100}
+
+	for {
+		resp, err := client.Block.GetChildren(context.Background(), notionapi.BlockID(pageID), &pagination)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, block := range resp.Results {
+			ids = append(ids, string(block.GetID()))
+
+			switch b := block.(type) {
+			case *notionapi.ChildPageBlock:
+				if !filter.Allows(sync.PageInfo{ID: string(b.ID), Title: b.ChildPage.Title}) {
+					continue
+				}
+				items = append(items, Item{
+					title:    b.ChildPage.Title,
+					id:       string(b.ID),
+					itemType: "page",
+					leaf:     !b.HasChildren,
+
+					lastEditedTime: lo.FromPtr(b.LastEditedTime),
+				})
+			case *notionapi.ChildDatabaseBlock:
+				if filter.Excludes(sync.PageInfo{ID: string(b.ID), Title: b.ChildDatabase.Title}) {
+					continue
+				}
+				items = append(items, Item{
+					title:    b.ChildDatabase.Title,
+					id:       string(b.ID),
+					itemType: "database",
+				})
+			}
+		}
+
+		if !resp.HasMore || resp.NextCursor == "" {
+			return items, ids, nil
+		}
+		pagination.StartCursor = notionapi.Cursor(resp.NextCursor)
+	}
+}
+
+// fetchDatabaseItems lists the rows of a database, and returns the IDs of all of them, excluded or not
+func fetchDatabaseItems(client *notionapi.Client, filter *sync.PageFilter, databaseID string) ([]Item, []string, error) {
+	items := make([]Item, 0)
+	var ids []string
+	request := notionapi.DatabaseQueryRequest{PageSize: 100}
+
+	for {
+		resp, err := client.Database.Query(context.Background(), notionapi.DatabaseID(databaseID), &request)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for i := range resp.Results {
+			row := &resp.Results[i]
+			ids = append(ids, string(row.ID))
+			title := sync.PageTitle(row)
+			info := sync.PageInfo{ID: string(row.ID), Title: title, ParentID: databaseID, Properties: row.Properties}
+			if !filter.Allows(info) {
+				continue
+			}
 			items = append(items, Item{
-				title:    b.ChildDatabase.Title,
-				id:       string(b.ID),
-				itemType: "database",
+				title:    title,
+				id:       string(row.ID),
+				itemType: "page",
+
+				lastEditedTime: row.LastEditedTime,
 			})
 		}
-	}
 
-	if len(items) == 0 {
-		return error(nil)
+		if !resp.HasMore || resp.NextCursor == "" {
+			return items, ids, nil
+		}
+		request.StartCursor = resp.NextCursor
 	}
+}
 
-	return items
+// Aborted reports whether the selection screen was quit without starting the sync
+func (m SelectionModel) Aborted() bool {
+	return m.aborted
 }
 
+// GetSelectedPages returns the IDs of the selected pages and databases, including the preselected
+// pages that were never loaded
 func (m SelectionModel) GetSelectedPages() []string {
 	selected := make([]string, 0)
-	for id, isSelected := range m.selected {
-		if isSelected {
+	for id, node := range m.nodes {
+		if node.selected {
 			selected = append(selected, id)
 		}
 	}
+
+	for id := range m.preselected {
+		selected = append(selected, id)
+	}
+
 	return selected
 }
@@ -0,0 +1,268 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/spf13/viper"
+)
+
+// KeyMap defines all keybindings
+type KeyMap struct {
+	Toggle   key.Binding
+	Subtree  key.Binding
+	Expand   key.Binding
+	Collapse key.Binding
+	All      key.Binding
+	None     key.Binding
+	Invert   key.Binding
+	Filtered key.Binding
+	Changed  key.Binding
+	Sort     key.Binding
+	Preview  key.Binding
+	Down     key.Binding
+	Up       key.Binding
+	Help     key.Binding
+	Quit     key.Binding
+	Start    key.Binding
+}
+
+var DefaultKeyMap = KeyMap{
+	Toggle: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "toggle selection"),
+	),
+	Subtree: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "toggle with sub-pages"),
+	),
+	Expand: key.NewBinding(
+		key.WithKeys("right", "l"),
+		key.WithHelp("→/l", "expand"),
+	),
+	Collapse: key.NewBinding(
+		key.WithKeys("left", "h"),
+		key.WithHelp("←/h", "collapse"),
+	),
+	All: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "select all"),
+	),
+	None: key.NewBinding(
+		key.WithKeys("n"),
+		key.WithHelp("n", "select none"),
+	),
+	Invert: key.NewBinding(
+		key.WithKeys("i"),
+		key.WithHelp("i", "invert selection"),
+	),
+	Filtered: key.NewBinding(
+		key.WithKeys("F"),
+		key.WithHelp("F", "select filtered"),
+	),
+	Changed: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "select new and changed pages"),
+	),
+	Sort: key.NewBinding(
+		key.WithKeys("o"),
+		key.WithHelp("o", "sort by title/edit date"),
+	),
+	Preview: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "toggle preview"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("ctrl+d"),
+		key.WithHelp("ctrl+d", "scroll preview down"),
+	),
+	Up: key.NewBinding(
+		key.WithKeys("ctrl+u"),
+		key.WithHelp("ctrl+u", "scroll preview up"),
+	),
+	Help: key.NewBinding(
+		key.WithKeys("?"),
+		key.WithHelp("?", "toggle help"),
+	),
+	// esc is left to the list, to clear the filter
+	Quit: key.NewBinding(
+		key.WithKeys("q", "ctrl+c"),
+		key.WithHelp("q", "quit"),
+	),
+	Start: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "start sync"),
+	),
+}
+
+// bindings maps the name of each action, as used in the configuration, to its binding
+func (k *KeyMap) bindings() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"toggle":   &k.Toggle,
+		"subtree":  &k.Subtree,
+		"expand":   &k.Expand,
+		"collapse": &k.Collapse,
+		"all":      &k.All,
+		"none":     &k.None,
+		"invert":   &k.Invert,
+		"filtered": &k.Filtered,
+		"changed":  &k.Changed,
+		"sort":     &k.Sort,
+		"preview":  &k.Preview,
+		"down":     &k.Down,
+		"up":       &k.Up,
+		"help":     &k.Help,
+		"quit":     &k.Quit,
+		"start":    &k.Start,
+	}
+}
+
+// LoadKeyMap returns the default keymap, overridden by the "keys" setting which maps actions to
+// their keys, e.g. toggle: [space, x]. An action without keys is disabled.
+func LoadKeyMap() (KeyMap, error) {
+	keymap := DefaultKeyMap
+	bindings := keymap.bindings()
+
+	for action, keys := range viper.GetStringMapStringSlice("keys") {
+		binding, ok := bindings[action]
+		if !ok {
+			actions := make([]string, 0, len(bindings))
+			for name := range bindings {
+				actions = append(actions, name)
+			}
+			slices.Sort(actions)
+			return keymap, fmt.Errorf("unknown key binding %q, expected one of: %s", action, strings.Join(actions, ", "))
+		}
+
+		if len(keys) == 0 {
+			binding.SetEnabled(false)
+			continue
+		}
+
+		// Bubble Tea names the space bar " "
+		keyNames := make([]string, len(keys))
+		for i, k := range keys {
+			keyNames[i] = k
+			if k == "space" {
+				keyNames[i] = " "
+			}
+		}
+
+		binding.SetKeys(keyNames...)
+		binding.SetHelp(strings.Join(keys, "/"), binding.Help().Desc)
+	}
+
+	if err := keymap.validate(); err != nil {
+		return DefaultKeyMap, err
+	}
+
+	return keymap, nil
+}
+
+// validate rejects a key bound to several actions, only one of which could ever run, and keymaps
+// leaving no key to the list bindings that cannot be replaced, such as pagination
+func (k *KeyMap) validate() error {
+	bindings := k.bindings()
+	actions := make([]string, 0, len(bindings))
+	for name := range bindings {
+		actions = append(actions, name)
+	}
+	slices.Sort(actions)
+
+	used := make(map[string]string)
+	for _, action := range actions {
+		binding := bindings[action]
+		if !binding.Enabled() {
+			continue
+		}
+		for _, k := range binding.Keys() {
+			if other, ok := used[k]; ok {
+				return fmt.Errorf("key %q is bound to both %s and %s", keyName(k), other, action)
+			}
+			used[k] = action
+		}
+	}
+
+	defaults := list.DefaultKeyMap()
+	listKeys := k.listKeyMap(defaults)
+	for _, required := range []struct {
+		binding  key.Binding
+		defaults key.Binding
+	}{
+		{listKeys.CursorUp, defaults.CursorUp},
+		{listKeys.CursorDown, defaults.CursorDown},
+		{listKeys.PrevPage, defaults.PrevPage},
+		{listKeys.NextPage, defaults.NextPage},
+		{listKeys.Filter, defaults.Filter},
+	} {
+		if len(required.binding.Keys()) == 0 {
+			return fmt.Errorf("no key left for %s, %s are all bound to actions", required.defaults.Help().Desc, strings.Join(required.defaults.Keys(), "/"))
+		}
+	}
+
+	return nil
+}
+
+// listKeyMap returns the list bindings without the keys handled by the selection screen, which
+// never reach the list. With the default keymap, pages are turned with pgup/b/u and pgdown/f/d, as
+// left/h and right/l collapse and expand pages.
+func (k *KeyMap) listKeyMap(listKeys list.KeyMap) list.KeyMap {
+	used := make(map[string]bool)
+	for _, binding := range k.bindings() {
+		if !binding.Enabled() {
+			continue
+		}
+		for _, k := range binding.Keys() {
+			used[k] = true
+		}
+	}
+
+	for _, binding := range []*key.Binding{&listKeys.CursorUp, &listKeys.CursorDown, &listKeys.PrevPage, &listKeys.NextPage, &listKeys.GoToStart, &listKeys.GoToEnd, &listKeys.Filter, &listKeys.ClearFilter} {
+		keys := slices.DeleteFunc(slices.Clone(binding.Keys()), func(k string) bool { return used[k] })
+		if len(keys) == len(binding.Keys()) {
+			continue
+		}
+		binding.SetKeys(keys...)
+		binding.SetHelp(strings.Join(keys, "/"), binding.Help().Desc)
+	}
+
+	return listKeys
+}
+
+// keyName returns the name of a key as written in the configuration
+func keyName(k string) string {
+	if k == " " {
+		return "space"
+	}
+	return k
+}
+
+// selectionHelp lists the bindings of the selection screen, along with those of the list
+type selectionHelp struct {
+	keymap KeyMap
+	list   list.KeyMap
+}
+
+func (h selectionHelp) ShortHelp() []key.Binding {
+	return []key.Binding{
+		h.keymap.Toggle,
+		h.keymap.Subtree,
+		h.keymap.Expand,
+		h.list.Filter,
+		h.keymap.Start,
+		h.keymap.Quit,
+		h.keymap.Help,
+	}
+}
+
+func (h selectionHelp) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{h.list.CursorUp, h.list.CursorDown, h.list.PrevPage, h.list.NextPage, h.keymap.Expand, h.keymap.Collapse, h.list.Filter, h.list.ClearFilter},
+		{h.keymap.Toggle, h.keymap.Subtree, h.keymap.All, h.keymap.None, h.keymap.Invert, h.keymap.Filtered, h.keymap.Changed},
+		{h.keymap.Sort, h.keymap.Preview, h.keymap.Down, h.keymap.Up},
+		{h.keymap.Start, h.keymap.Quit, h.keymap.Help},
+	}
+}
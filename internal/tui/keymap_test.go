@@ -0,0 +1,87 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/spf13/viper"
+)
+
+func TestLoadKeyMap(t *testing.T) {
+	tests := []struct {
+		name         string
+		keys         map[string]interface{}
+		wantErr      string
+		wantPrevPage []string
+		wantNextPage []string
+	}{
+		{
+			name:         "defaults",
+			wantPrevPage: []string{"pgup", "b", "u"},
+			wantNextPage: []string{"pgdown", "f", "d"},
+		},
+		{
+			name:         "expand and collapse moved",
+			keys:         map[string]interface{}{"expand": []string{"e"}, "collapse": []string{"w"}},
+			wantPrevPage: []string{"left", "h", "pgup", "b", "u"},
+			wantNextPage: []string{"right", "l", "pgdown", "f", "d"},
+		},
+		{
+			name:    "key bound twice",
+			keys:    map[string]interface{}{"toggle": []string{"space", "a"}},
+			wantErr: `key "a" is bound to both all and toggle`,
+		},
+		{
+			name:    "space bound twice",
+			keys:    map[string]interface{}{"subtree": []string{"space"}},
+			wantErr: `key "space" is bound to both subtree and toggle`,
+		},
+		{
+			name:         "disabled action",
+			keys:         map[string]interface{}{"all": []string{}, "toggle": []string{"a"}},
+			wantPrevPage: []string{"pgup", "b", "u"},
+			wantNextPage: []string{"pgdown", "f", "d"},
+		},
+		{
+			name:    "pagination shadowed",
+			keys:    map[string]interface{}{"collapse": []string{"left", "h", "pgup", "b", "u"}},
+			wantErr: "no key left for prev page",
+		},
+		{
+			name:    "unknown action",
+			keys:    map[string]interface{}{"jump": []string{"j"}},
+			wantErr: `unknown key binding "jump"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			if tt.keys != nil {
+				viper.Set("keys", tt.keys)
+			}
+
+			keymap, err := LoadKeyMap()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadKeyMap() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadKeyMap() error = %v", err)
+			}
+
+			listKeys := keymap.listKeyMap(list.DefaultKeyMap())
+			if got := listKeys.PrevPage.Keys(); !slices.Equal(got, tt.wantPrevPage) {
+				t.Errorf("prev page keys = %v, want %v", got, tt.wantPrevPage)
+			}
+			if got := listKeys.NextPage.Keys(); !slices.Equal(got, tt.wantNextPage) {
+				t.Errorf("next page keys = %v, want %v", got, tt.wantNextPage)
+			}
+		})
+	}
+}
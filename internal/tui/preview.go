@@ -0,0 +1,126 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ma111e/hugo-notion/internal/sync"
+)
+
+// Delay before the highlighted page is fetched, so scrolling through the list stays cheap
+const previewDelay = 300 * time.Millisecond
+
+var previewStyle = lipgloss.NewStyle().
+	Border(lipgloss.RoundedBorder()).
+	BorderForeground(lipgloss.Color("240"))
+
+// previewTickMsg triggers the fetch of a page if it is still highlighted
+type previewTickMsg struct {
+	id string
+}
+
+// previewMsg carries the Markdown of a page, once converted
+type previewMsg struct {
+	id       string
+	markdown string
+	err      error
+}
+
+// MarkdownCache returns the pages converted for the preview, to be reused by the sync
+func (m SelectionModel) MarkdownCache() *sync.MarkdownCache {
+	return m.markdownCache
+}
+
+// resize splits the screen between the list and the preview
+func (m *SelectionModel) resize() {
+	m.help.Width = m.width
+	height := m.height - lipgloss.Height(m.helpView()) - 1
+
+	listWidth := m.width
+	if m.showPreview {
+		listWidth = m.width / 2
+		m.preview.Width = m.width - listWidth - previewStyle.GetHorizontalFrameSize()
+		m.preview.Height = height - previewStyle.GetVerticalFrameSize()
+	}
+
+	m.list.SetWidth(listWidth)
+	m.list.SetHeight(height)
+	m.renderPreview()
+}
+
+// schedulePreview starts loading the preview of the highlighted page when it changes
+func (m *SelectionModel) schedulePreview() tea.Cmd {
+	if !m.showPreview {
+		return nil
+	}
+
+	var id string
+	if node := m.currentNode(); node != nil {
+		id = node.id
+	}
+	if id == m.previewID {
+		return nil
+	}
+
+	m.previewID = id
+	m.renderPreview()
+
+	if _, ok := m.previews[id]; ok || id == "" || m.nodes[id].itemType == "database" {
+		return nil
+	}
+
+	return tea.Tick(previewDelay, func(time.Time) tea.Msg {
+		return previewTickMsg{id: id}
+	})
+}
+
+// fetchPreview converts the page to Markdown, through the cache shared with the sync
+func (m SelectionModel) fetchPreview(id string) tea.Cmd {
+	client := m.client
+	cache := m.markdownCache
+	lastEditedTime := m.nodes[id].lastEditedTime
+
+	return func() tea.Msg {
+		markdown, err := sync.PageMarkdown(client, cache, id, lastEditedTime)
+		return previewMsg{id: id, markdown: markdown, err: err}
+	}
+}
+
+// renderPreview displays the preview of the highlighted page
+func (m *SelectionModel) renderPreview() {
+	if !m.showPreview || m.preview.Width <= 0 {
+		return
+	}
+
+	node := m.nodes[m.previewID]
+	markdown, ok := m.previews[m.previewID]
+
+	switch {
+	case node == nil:
+		m.preview.SetContent("")
+	case node.itemType == "database":
+		m.preview.SetContent(fmt.Sprintf("%s is a database: expand it to preview its rows.", node.title))
+	case !ok:
+		m.preview.SetContent("Loading preview...")
+	default:
+		style := styles.DarkStyle
+		if !m.darkBackground {
+			style = styles.LightStyle
+		}
+
+		rendered := markdown
+		renderer, err := glamour.NewTermRenderer(glamour.WithStandardStyle(style), glamour.WithWordWrap(m.preview.Width-2))
+		if err == nil {
+			if out, err := renderer.Render("# " + node.title + "\n\n" + markdown); err == nil {
+				rendered = out
+			}
+		}
+		m.preview.SetContent(rendered)
+	}
+
+	m.preview.GotoTop()
+}
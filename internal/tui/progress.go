@@ -0,0 +1,76 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Statuses counted while syncing, in the order they are shown
+var progressStatuses = []string{"Created", "Updated", "Skipped", "Error", "Deleted", "Warning", "Conflict"}
+
+// progressView shows how far the sync has gone: the progress bar, the page being synced, the
+// results so far and the downloaded images
+func (m syncModel) progressView() string {
+	var s strings.Builder
+
+	p := m.progress
+	if p.Total == 0 {
+		s.WriteString(fmt.Sprintf("%s Listing Notion pages...\n", m.spinner.View()))
+		return s.String()
+	}
+
+	current := "Syncing Notion pages..."
+	if p.Current != "" {
+		current = fmt.Sprintf("Syncing %q", p.Current)
+	}
+	s.WriteString(fmt.Sprintf("%s %s\n", m.spinner.View(), current))
+
+	s.WriteString(m.progressBar.ViewAs(float64(p.Done) / float64(p.Total)))
+	s.WriteString(fmt.Sprintf("  %d/%d pages", p.Done, p.Total))
+	if eta, ok := m.eta(); ok {
+		s.WriteString(fmt.Sprintf(" · ETA %s", eta))
+	}
+	s.WriteString("\n")
+
+	counts := make(map[string]int)
+	for _, r := range m.results {
+		counts[strings.ToLower(r.Status)]++
+	}
+	var counters []string
+	for _, status := range progressStatuses {
+		counters = append(counters, m.statusStyle(status).Render(fmt.Sprintf("%s %d", status, counts[strings.ToLower(status)])))
+	}
+	s.WriteString(strings.Join(counters, " · ") + "\n")
+
+	s.WriteString(fmt.Sprintf("Images downloaded: %s\n", formatBytes(p.ImageBytes)))
+
+	return s.String()
+}
+
+// eta estimates the time left from the average time spent per page so far
+func (m syncModel) eta() (time.Duration, bool) {
+	p := m.progress
+	if p.Done == 0 || p.Done >= p.Total || p.Started.IsZero() {
+		return 0, false
+	}
+
+	perPage := time.Since(p.Started) / time.Duration(p.Done)
+	return (perPage * time.Duration(p.Total-p.Done)).Round(time.Second), true
+}
+
+// formatBytes shows a size with a binary unit, e.g. 1.5 MiB
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
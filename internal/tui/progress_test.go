@@ -0,0 +1,70 @@
+package tui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ma111e/hugo-notion/internal/sync"
+)
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{size: 0, want: "0 B"},
+		{size: 1023, want: "1023 B"},
+		{size: 1024, want: "1.0 KiB"},
+		{size: 1536 * 1024, want: "1.5 MiB"},
+		{size: 3 << 30, want: "3.0 GiB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := formatBytes(tt.size); got != tt.want {
+				t.Errorf("formatBytes(%d) = %q, want %q", tt.size, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestETA(t *testing.T) {
+	started := time.Now().Add(-20 * time.Second)
+
+	tests := []struct {
+		name     string
+		progress sync.Progress
+		want     time.Duration
+		wantOK   bool
+	}{
+		{
+			name:     "no page synced yet",
+			progress: sync.Progress{Total: 4, Started: started},
+		},
+		{
+			name:     "half of the pages synced",
+			progress: sync.Progress{Total: 4, Done: 2, Started: started},
+			want:     20 * time.Second,
+			wantOK:   true,
+		},
+		{
+			name:     "all pages synced",
+			progress: sync.Progress{Total: 4, Done: 4, Started: started},
+		},
+		{
+			name:     "not started",
+			progress: sync.Progress{Total: 4, Done: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := syncModel{progress: tt.progress}
+
+			got, ok := m.eta()
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("eta() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
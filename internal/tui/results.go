@@ -0,0 +1,343 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ma111e/hugo-notion/internal/sync"
+)
+
+// resultsKeyMap defines the keybindings of the results screen
+type resultsKeyMap struct {
+	Filter  key.Binding
+	Clear   key.Binding
+	Details key.Binding
+	Diff    key.Binding
+	Edit    key.Binding
+	Retry   key.Binding
+	Up      key.Binding
+	Down    key.Binding
+	Help    key.Binding
+	Quit    key.Binding
+}
+
+var defaultResultsKeyMap = resultsKeyMap{
+	Filter: key.NewBinding(
+		key.WithKeys("/"),
+		key.WithHelp("/", "filter"),
+	),
+	Clear: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "clear filter/back"),
+	),
+	Details: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "details"),
+	),
+	Diff: key.NewBinding(
+		key.WithKeys("d"),
+		key.WithHelp("d", "diff updated page/conflict"),
+	),
+	Edit: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "open in $EDITOR"),
+	),
+	Retry: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "retry failed pages"),
+	),
+	Up: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑/k", "up"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓/j", "down"),
+	),
+	Help: key.NewBinding(
+		key.WithKeys("?"),
+		key.WithHelp("?", "toggle help"),
+	),
+	Quit: key.NewBinding(
+		key.WithKeys("q", "ctrl+c"),
+		key.WithHelp("q", "quit"),
+	),
+}
+
+func (k resultsKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Details, k.Filter, k.Retry, k.Edit, k.Diff, k.Help, k.Quit}
+}
+
+func (k resultsKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Up, k.Down, k.Details, k.Filter, k.Clear},
+		{k.Retry, k.Edit, k.Diff},
+		{k.Help, k.Quit},
+	}
+}
+
+// retryMsg carries the results of the pages synced again
+type retryMsg struct {
+	pageIDs []string
+	results []sync.SyncResult
+}
+
+// editorFinishedMsg is sent when the editor opened on a post exits
+type editorFinishedMsg struct {
+	err error
+}
+
+// handleKey runs the actions of the results screen, once the sync is done
+func (m syncModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if m.filtering {
+		switch msg.String() {
+		case "esc":
+			m.filter.SetValue("")
+			fallthrough
+		case "enter":
+			m.filtering = false
+			m.filter.Blur()
+			m.updateTable()
+			return m, nil
+		}
+
+		m.filter, cmd = m.filter.Update(msg)
+		m.updateTable()
+		return m, cmd
+	}
+
+	if m.showDetail {
+		switch {
+		case key.Matches(msg, m.keymap.Quit), key.Matches(msg, m.keymap.Clear), key.Matches(msg, m.keymap.Details):
+			m.showDetail = false
+			return m, nil
+		case key.Matches(msg, m.keymap.Help):
+			m.help.ShowAll = !m.help.ShowAll
+			m.resize()
+			return m, nil
+		}
+
+		m.detail, cmd = m.detail.Update(msg)
+		return m, cmd
+	}
+
+	m.notice = ""
+
+	switch {
+	case key.Matches(msg, m.keymap.Quit):
+		m.quitting = true
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keymap.Help):
+		m.help.ShowAll = !m.help.ShowAll
+		m.resize()
+		return m, nil
+
+	case key.Matches(msg, m.keymap.Filter):
+		m.filtering = true
+		m.resize()
+		return m, m.filter.Focus()
+
+	case key.Matches(msg, m.keymap.Clear):
+		m.filter.SetValue("")
+		m.updateTable()
+		return m, nil
+
+	case key.Matches(msg, m.keymap.Details):
+		if result, ok := m.selectedResult(); ok {
+			m.openDetail(result.PageTitle, m.resultDetails(result))
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keymap.Diff):
+		result, ok := m.selectedResult()
+		if !ok {
+			return m, nil
+		}
+		if result.Previous == "" {
+			m.notice = "Only updated pages and conflicts can be diffed"
+			return m, nil
+		}
+
+		diff, err := resultDiff(result)
+		if err != nil {
+			m.notice = fmt.Sprintf("Failed to read %s: %v", result.Path, err)
+			return m, nil
+		}
+		m.openDetail("Changes to "+result.Path, diff)
+		return m, nil
+
+	case key.Matches(msg, m.keymap.Edit):
+		result, ok := m.selectedResult()
+		if !ok {
+			return m, nil
+		}
+		return m, m.openEditor(result.Path)
+
+	case key.Matches(msg, m.keymap.Retry):
+		if m.retrying {
+			return m, nil
+		}
+		if m.retry == nil {
+			m.notice = "Retrying is not available"
+			return m, nil
+		}
+
+		pageIDs := m.failedPages()
+		if len(pageIDs) == 0 {
+			m.notice = "No failed pages to retry"
+			return m, nil
+		}
+
+		m.retrying = true
+		retry := m.retry
+		return m, tea.Batch(m.spinner.Tick, func() tea.Msg {
+			return retryMsg{pageIDs: pageIDs, results: retry(pageIDs)}
+		})
+	}
+
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+// selectedResult returns the result highlighted in the table
+func (m syncModel) selectedResult() (sync.SyncResult, bool) {
+	cursor := m.table.Cursor()
+	if cursor < 0 || cursor >= len(m.visible) {
+		return sync.SyncResult{}, false
+	}
+
+	return m.results[m.visible[cursor]], true
+}
+
+// resultDiff renders the changes from the former content of a post to its current content.
+// Posts in conflict kept locally are compared with the Notion version that was not written.
+func resultDiff(result sync.SyncResult) (string, error) {
+	current := result.Incoming
+	if current == "" {
+		content, err := os.ReadFile(result.Path)
+		if err != nil {
+			return "", err
+		}
+		current = string(content)
+	}
+
+	return renderDiff(result.Previous, current), nil
+}
+
+// matchesFilter reports whether a result contains the filter in its title, status, path or message
+func (m syncModel) matchesFilter(r sync.SyncResult) bool {
+	filter := strings.ToLower(strings.TrimSpace(m.filter.Value()))
+	if filter == "" {
+		return true
+	}
+
+	return slices.ContainsFunc([]string{r.PageTitle, r.Status, r.Path, r.Message}, func(field string) bool {
+		return strings.Contains(strings.ToLower(field), filter)
+	})
+}
+
+// failedPages returns the IDs of the pages which failed to sync
+func (m syncModel) failedPages() []string {
+	var pageIDs []string
+	for _, r := range m.results {
+		if strings.EqualFold(r.Status, "error") && r.PageID != "" && !slices.Contains(pageIDs, r.PageID) {
+			pageIDs = append(pageIDs, r.PageID)
+		}
+	}
+
+	return pageIDs
+}
+
+// replaceResults swaps the results of the retried pages for the new ones
+func (m *syncModel) replaceResults(pageIDs []string, results []sync.SyncResult) {
+	m.results = slices.DeleteFunc(m.results, func(r sync.SyncResult) bool {
+		return slices.Contains(pageIDs, r.PageID)
+	})
+	m.results = append(m.results, results...)
+}
+
+// resultDetails describes a result in full, as the table truncates its columns
+func (m syncModel) resultDetails(r sync.SyncResult) string {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("Status:       %s\n", r.Status))
+	s.WriteString(fmt.Sprintf("Page:         %s\n", r.PageTitle))
+	if r.PageID != "" {
+		s.WriteString(fmt.Sprintf("Page ID:      %s\n", r.PageID))
+	}
+	s.WriteString(fmt.Sprintf("Path:         %s\n", r.Path))
+	s.WriteString(fmt.Sprintf("Last updated: %s\n", r.LastUpdated.Format("2006-01-02 15:04:05")))
+	if r.Message != "" {
+		s.WriteString("\n" + lipgloss.NewStyle().Width(max(m.width, 40)).Render(r.Message) + "\n")
+	}
+
+	return s.String()
+}
+
+// openDetail shows a text in place of the table, scrollable in a viewport
+func (m *syncModel) openDetail(title string, content string) {
+	m.showDetail = true
+	m.detailTitle = title
+	m.resize()
+	m.detail.SetContent(content)
+	m.detail.GotoTop()
+}
+
+// openEditor suspends the screen to edit a post with $VISUAL or $EDITOR
+func (m *syncModel) openEditor(path string) tea.Cmd {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		m.notice = fmt.Sprintf("%s is not a file", path)
+		return nil
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// The editor may come with arguments, e.g. "code --wait"
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return editorFinishedMsg{err: err}
+	})
+}
+
+// helpView renders the keybindings of the results screen
+func (m syncModel) helpView() string {
+	return m.help.View(m.keymap)
+}
+
+// resize fits the table, or the details, to the height of the window
+func (m *syncModel) resize() {
+	if !m.resultsScreen || m.height == 0 {
+		return
+	}
+
+	m.help.Width = m.width
+	const headerHeight = 3
+
+	if m.showDetail {
+		m.detail.Width = m.width
+		m.detail.Height = max(m.height-headerHeight-2-lipgloss.Height(m.helpView())-1, 1)
+		return
+	}
+
+	columns := m.table.Columns()
+	columns[2].Width = max(m.width-65, 40)
+	m.table.SetColumns(columns)
+	m.table.SetHeight(max(m.height-headerHeight-lipgloss.Height(m.footerView())-1, 3))
+}
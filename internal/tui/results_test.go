@@ -0,0 +1,81 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ma111e/hugo-notion/internal/sync"
+)
+
+func TestResultDiff(t *testing.T) {
+	dir := t.TempDir()
+	post := filepath.Join(dir, "post.md")
+	if err := os.WriteFile(post, []byte("title\nedited locally\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	notionFile := post + ".notion"
+	if err := os.WriteFile(notionFile, []byte("title\nedited in Notion\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		result  sync.SyncResult
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "conflict kept locally",
+			result: sync.SyncResult{
+				Status:   "Conflict",
+				Path:     post,
+				Previous: "title\nedited locally\n",
+				Incoming: "title\nedited in Notion\n",
+			},
+			want: []string{"-edited locally", "+edited in Notion"},
+		},
+		{
+			name: "conflict written side by side",
+			result: sync.SyncResult{
+				Status:   "Conflict",
+				Path:     notionFile,
+				Previous: "title\nedited locally\n",
+			},
+			want: []string{"-edited locally", "+edited in Notion"},
+		},
+		{
+			name: "updated post",
+			result: sync.SyncResult{
+				Status:   "Updated",
+				Path:     post,
+				Previous: "title\nsynced\n",
+			},
+			want: []string{"-synced", "+edited locally"},
+		},
+		{
+			name: "missing post",
+			result: sync.SyncResult{
+				Status:   "Updated",
+				Path:     filepath.Join(dir, "missing.md"),
+				Previous: "title\n",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resultDiff(tt.result)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resultDiff() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, line := range tt.want {
+				if !strings.Contains(got, line) {
+					t.Errorf("resultDiff() = %q, want a line %q", got, line)
+				}
+			}
+		})
+	}
+}
@@ -2,8 +2,12 @@ package tui
 
 import (
 	"fmt"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/term"
@@ -13,6 +17,9 @@ import (
 	"time"
 )
 
+// RetryFunc syncs the given pages again and returns the new results
+type RetryFunc func(pageIDs []string) []sync.SyncResult
+
 type syncModel struct {
 	table     table.Model
 	results   []sync.SyncResult
@@ -21,14 +28,36 @@ type syncModel struct {
 	isLoading bool
 	spinner   spinner.Model
 	styles    statusStyles
+
+	// Progress of the sync, streamed by the syncer
+	progress    sync.Progress
+	progressBar progress.Model
+
+	// The results screen stays open once the sync is done, until the user quits it
+	resultsScreen bool
+	keymap        resultsKeyMap
+	help          help.Model
+	filter        textinput.Model
+	filtering     bool
+	visible       []int // Indexes of the results matching the filter, as shown in the table
+	detail        viewport.Model
+	detailTitle   string
+	showDetail    bool
+	retry         RetryFunc
+	retrying      bool
+	notice        string
+	width         int
+	height        int
 }
 
 type statusStyles struct {
-	created lipgloss.Style
-	updated lipgloss.Style
-	skipped lipgloss.Style
-	error   lipgloss.Style
-	deleted lipgloss.Style
+	created  lipgloss.Style
+	updated  lipgloss.Style
+	skipped  lipgloss.Style
+	error    lipgloss.Style
+	deleted  lipgloss.Style
+	warning  lipgloss.Style
+	conflict lipgloss.Style
 }
 
 func NewSyncModel() syncModel {
@@ -68,13 +97,18 @@ func NewSyncModel() syncModel {
 
 	// Define status styles
 	styles := statusStyles{
-		created: lipgloss.NewStyle().Foreground(lipgloss.Color("2")),  // Green
-		updated: lipgloss.NewStyle().Foreground(lipgloss.Color("6")),  // Cyan
-		skipped: lipgloss.NewStyle().Foreground(lipgloss.Color("3")),  // Yellow
-		error:   lipgloss.NewStyle().Foreground(lipgloss.Color("1")),  // Red
-		deleted: lipgloss.NewStyle().Foreground(lipgloss.Color("13")), // Purple
+		created:  lipgloss.NewStyle().Foreground(lipgloss.Color("2")),   // Green
+		updated:  lipgloss.NewStyle().Foreground(lipgloss.Color("6")),   // Cyan
+		skipped:  lipgloss.NewStyle().Foreground(lipgloss.Color("3")),   // Yellow
+		error:    lipgloss.NewStyle().Foreground(lipgloss.Color("1")),   // Red
+		deleted:  lipgloss.NewStyle().Foreground(lipgloss.Color("13")),  // Purple
+		warning:  lipgloss.NewStyle().Foreground(lipgloss.Color("208")), // Orange
+		conflict: lipgloss.NewStyle().Foreground(lipgloss.Color("9")),   // Bright red
 	}
 
+	filter := textinput.New()
+	filter.Prompt = "Filter: "
+
 	return syncModel{
 		table:     t,
 		results:   make([]sync.SyncResult, 0),
@@ -82,9 +116,27 @@ func NewSyncModel() syncModel {
 		isLoading: true,
 		spinner:   sp,
 		styles:    styles,
+		keymap:    defaultResultsKeyMap,
+		help:      help.New(),
+		filter:    filter,
+		detail:    viewport.New(0, 0),
+		width:     width,
+
+		progressBar: progress.New(progress.WithDefaultGradient(), progress.WithWidth(min(width-4, 60))),
 	}
 }
 
+// SetResultsScreen keeps the results open once the sync is done, for the user to browse them
+func (m *syncModel) SetResultsScreen(enabled bool) {
+	m.resultsScreen = enabled
+	m.table.Focus()
+}
+
+// SetRetry sets how failed pages are synced again from the results screen
+func (m *syncModel) SetRetry(retry RetryFunc) {
+	m.retry = retry
+}
+
 func (m syncModel) Init() tea.Cmd {
 	return m.spinner.Tick
 }
@@ -94,25 +146,65 @@ func (m syncModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case spinner.TickMsg:
-		if m.isLoading {
+		if m.isLoading || m.retrying {
 			var spinnerCmd tea.Cmd
 			m.spinner, spinnerCmd = m.spinner.Update(msg)
 			return m, spinnerCmd
 		}
 		return m, nil
 
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.resize()
+		return m, nil
+
 	case tea.KeyMsg:
-		if msg.String() == "q" || msg.String() == "ctrl+c" {
+		if msg.String() == "ctrl+c" || (!m.resultsScreen && msg.String() == "q") {
 			m.quitting = true
 			return m, tea.Quit
 		}
+		if m.resultsScreen && !m.isLoading {
+			return m.handleKey(msg)
+		}
+
+	case sync.Progress:
+		m.progress = msg
+		return m, nil
+
+	case sync.SyncResult:
+		// Results streamed while syncing, until the final list replaces them. Late ones are
+		// already in the final list.
+		if m.isLoading {
+			m.results = append(m.results, msg)
+			m.updateTable()
+		}
+		return m, nil
 
 	case []sync.SyncResult:
 		m.results = msg
 		m.isLoading = false
 		m.lastSync = time.Now()
 		m.updateTable()
-		return m, tea.Quit
+		if !m.resultsScreen {
+			return m, tea.Quit
+		}
+		m.resize()
+		return m, nil
+
+	case retryMsg:
+		m.retrying = false
+		m.replaceResults(msg.pageIDs, msg.results)
+		m.lastSync = time.Now()
+		m.notice = fmt.Sprintf("Retried %d page(s)", len(msg.pageIDs))
+		m.updateTable()
+		return m, nil
+
+	case editorFinishedMsg:
+		if msg.err != nil {
+			m.notice = fmt.Sprintf("Editor failed: %v", msg.err)
+		}
+		return m, nil
 	}
 
 	return m, cmd
@@ -123,49 +215,68 @@ func (m syncModel) View() string {
 	s.WriteString("\n 📝 Notion Sync Status\n\n")
 
 	if m.isLoading && len(m.results) == 0 {
-		s.WriteString(fmt.Sprintf("%s Syncing Notion pages...\n", m.spinner.View()))
+		s.WriteString(m.progressView())
+	} else if m.showDetail {
+		s.WriteString(" " + m.detailTitle + "\n\n")
+		s.WriteString(m.detail.View())
+		s.WriteString("\n\n" + m.helpView())
 	} else {
 		s.WriteString(m.table.View())
-		s.WriteString("\n\nStatus Legend:\n")
-		s.WriteString(fmt.Sprintf("  %s Created: New page added\n", m.styles.created.Render("●")))
-		s.WriteString(fmt.Sprintf("  %s Updated: Page content changed\n", m.styles.updated.Render("●")))
-		s.WriteString(fmt.Sprintf("  %s Skipped: Page content unchanged\n", m.styles.skipped.Render("●")))
-		s.WriteString(fmt.Sprintf("  %s Error: Failed to process page\n", m.styles.error.Render("●")))
-		s.WriteString(fmt.Sprintf("  %s Deleted: Page removed\n", m.styles.deleted.Render("●")))
-		s.WriteString(fmt.Sprintf("\nLast sync: %s", m.lastSync.Format("15:04:05")))
-		if m.isLoading {
-			s.WriteString(fmt.Sprintf("\n%s Still syncing...", m.spinner.View()))
-		}
+		s.WriteString("\n")
+		s.WriteString(m.footerView())
 	}
 
 	s.WriteString("\n")
 	return s.String()
 }
 
+// footerView shows the legend and the state of the results screen under the table
+func (m syncModel) footerView() string {
+	var s strings.Builder
+
+	s.WriteString("\nStatus Legend:\n")
+	s.WriteString(fmt.Sprintf("  %s Created: New page added\n", m.styles.created.Render("●")))
+	s.WriteString(fmt.Sprintf("  %s Updated: Page content changed\n", m.styles.updated.Render("●")))
+	s.WriteString(fmt.Sprintf("  %s Skipped: Page content unchanged\n", m.styles.skipped.Render("●")))
+	s.WriteString(fmt.Sprintf("  %s Error: Failed to process page\n", m.styles.error.Render("●")))
+	s.WriteString(fmt.Sprintf("  %s Deleted: Page removed\n", m.styles.deleted.Render("●")))
+	s.WriteString(fmt.Sprintf("  %s Warning: Page synced with issues\n", m.styles.warning.Render("●")))
+	s.WriteString(fmt.Sprintf("  %s Orphaned: File no longer used by its page\n", m.styles.warning.Render("●")))
+	s.WriteString(fmt.Sprintf("  %s Removed file: Unused file deleted from its page bundle\n", m.styles.deleted.Render("●")))
+	s.WriteString(fmt.Sprintf("  %s Conflict: Post edited locally\n", m.styles.conflict.Render("●")))
+	s.WriteString(fmt.Sprintf("\nLast sync: %s", m.lastSync.Format("15:04:05")))
+	if m.isLoading {
+		s.WriteString("\n\n" + m.progressView())
+	}
+
+	if m.resultsScreen && !m.isLoading {
+		if m.retrying {
+			s.WriteString(fmt.Sprintf(" · %s Retrying failed pages...", m.spinner.View()))
+		} else if m.notice != "" {
+			s.WriteString(" · " + m.notice)
+		}
+
+		if m.filtering || m.filter.Value() != "" {
+			s.WriteString(fmt.Sprintf("\n%s (%d/%d)", m.filter.View(), len(m.visible), len(m.results)))
+		}
+		s.WriteString("\n\n" + m.helpView())
+	}
+
+	return s.String()
+}
+
 func (m *syncModel) updateTable() {
-	rows := make([]table.Row, len(m.results))
+	m.visible = make([]int, 0, len(m.results))
 	for i, r := range m.results {
-		// Get the appropriate style for the status
-		var style lipgloss.Style
-
-		switch strings.ToLower(r.Status) {
-		case "created":
-			style = m.styles.created
-			style.Bold(true)
-		case "updated":
-			style = m.styles.updated
-			style.Bold(true)
-		case "skipped":
-			style = m.styles.skipped
-		case "error", "delete error":
-			style = m.styles.error
-		case "deleted":
-			style = m.styles.deleted
-			style.Bold(true)
-
-		default:
-			style = lipgloss.NewStyle()
+		if m.matchesFilter(r) {
+			m.visible = append(m.visible, i)
 		}
+	}
+
+	rows := make([]table.Row, len(m.visible))
+	for i, index := range m.visible {
+		r := m.results[index]
+		style := m.statusStyle(r.Status)
 
 		// Apply the style to both the page title and status
 		styledTitle := style.Render(r.PageTitle)
@@ -179,4 +290,29 @@ func (m *syncModel) updateTable() {
 		}
 	}
 	m.table.SetRows(rows)
+	m.resize()
+}
+
+// statusStyle returns the style of a result status
+func (m syncModel) statusStyle(status string) lipgloss.Style {
+	switch strings.ToLower(status) {
+	case "created":
+		return m.styles.created.Bold(true)
+	case "updated":
+		return m.styles.updated.Bold(true)
+	case "skipped":
+		return m.styles.skipped
+	case "error", "delete error":
+		return m.styles.error
+	case "deleted":
+		return m.styles.deleted.Bold(true)
+	case "removed file":
+		return m.styles.deleted
+	case "warning", "orphaned":
+		return m.styles.warning
+	case "conflict":
+		return m.styles.conflict.Bold(true)
+	}
+
+	return lipgloss.NewStyle()
 }